@@ -0,0 +1,172 @@
+package base
+
+import (
+  "bytes"
+  "encoding/hex"
+  "fmt"
+  "image"
+  "image/color"
+  _ "image/gif"   // register GIF decoding for ImageComparator
+  _ "image/jpeg"  // register JPEG decoding for ImageComparator
+  "image/png"
+  "os"
+  "strings"
+  "unicode/utf8"
+)
+
+// Comparator compares the content of an output file to the content of
+// its golden file.
+type Comparator interface {
+  // Compare returns nil if out matches golden, else an error describing
+  // the differences. The outfilepath is available for writing any
+  // additional artifacts next to the output file.
+  Compare(outfilepath string, out, golden []byte) error
+}
+
+// DiffReporter describes the differences between two contents.
+type DiffReporter interface {
+  Report(out, golden []byte) string
+}
+
+// DefaultComparator is the Comparator used by CompareOutToGolden.
+var DefaultComparator Comparator = BytesComparator{}
+
+// BytesComparator requires the output to be identical to the golden content.
+// On mismatch it describes the differences with its Reporter, or, if that
+// is nil, with a TextReporter for text content or a HexReporter for binary content.
+type BytesComparator struct {
+  Reporter DiffReporter
+}
+
+// Compare compares out to golden byte for byte.
+func (c BytesComparator) Compare(outfilepath string, out, golden []byte) error {
+  if bytes.Equal(out, golden) {
+    return nil
+  }
+  reporter := c.Reporter
+  if reporter == nil {
+    if isText(out) && isText(golden) {
+      reporter = TextReporter{}
+    } else {
+      reporter = HexReporter{}
+    }
+  }
+  return fmt.Errorf("differences (-golden +out):\n%s", reporter.Report(out, golden))
+}
+
+// isText returns true if b is valid UTF-8 with no NUL bytes.
+func isText(b []byte) bool {
+  return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+// TextReporter reports differences as a line diff, using TextDiff.
+type TextReporter struct{}
+
+// Report returns the line diff from golden to out.
+func (TextReporter) Report(out, golden []byte) string {
+  return TextDiff(string(golden), string(out))
+}
+
+// HexReporter reports differences as a diff of hex dumps.
+// Content longer than MaxBytes, default 4096, is summarized instead.
+type HexReporter struct {
+  MaxBytes int
+}
+
+// Report returns a diff of the hex dumps of golden and out, or, if either
+// is too long, their lengths and the offset of the first difference.
+func (r HexReporter) Report(out, golden []byte) string {
+  maxBytes := r.MaxBytes
+  if maxBytes <= 0 {
+    maxBytes = 4096
+  }
+  if len(out) > maxBytes || len(golden) > maxBytes {
+    offset := 0
+    for offset < len(out) && offset < len(golden) && out[offset] == golden[offset] {
+      offset++
+    }
+    return fmt.Sprintf("golden is %d bytes, out is %d bytes, first difference at offset %d (0x%x)\n",
+        len(golden), len(out), offset, offset)
+  }
+  return TextDiff(hex.Dump(golden), hex.Dump(out))
+}
+
+// ImageComparator decodes the output and golden content as images
+// (PNG, JPEG or GIF) and compares them pixel by pixel.
+// When the images differ, it writes a diff image next to the output file
+// (see DiffImagePath) showing the differing pixels in red over a faded
+// copy of the golden image.
+type ImageComparator struct {
+  // The maximum difference allowed in any 8-bit color channel for
+  // two pixels to be considered the same.
+  Tolerance uint8
+  // The number of differing pixels allowed before the images are
+  // considered different.
+  MaxDiffPixels int
+}
+
+// DiffImagePath returns the path to the diff image for an output file,
+// which is the output file path with its extension replaced by ".diff.png".
+func DiffImagePath(outfilepath string) string {
+  ext := ""
+  if i := strings.LastIndex(outfilepath, "."); i > strings.LastIndex(outfilepath, "/") {
+    ext = outfilepath[i:]
+  }
+  return strings.TrimSuffix(outfilepath, ext) + ".diff.png"
+}
+
+// Compare compares out to golden as images.
+func (c ImageComparator) Compare(outfilepath string, out, golden []byte) error {
+  diffpath := DiffImagePath(outfilepath)
+  os.Remove(diffpath)
+  outimg, _, err := image.Decode(bytes.NewReader(out))
+  if err != nil {
+    return fmt.Errorf("error decoding output image: %v", err)
+  }
+  goldenimg, _, err := image.Decode(bytes.NewReader(golden))
+  if err != nil {
+    return fmt.Errorf("error decoding golden image: %v", err)
+  }
+  ob, gb := outimg.Bounds(), goldenimg.Bounds()
+  if ob.Dx() != gb.Dx() || ob.Dy() != gb.Dy() {
+    return fmt.Errorf("image size: golden is %dx%d, out is %dx%d", gb.Dx(), gb.Dy(), ob.Dx(), ob.Dy())
+  }
+  diffimg := image.NewRGBA(image.Rect(0, 0, gb.Dx(), gb.Dy()))
+  count := 0
+  for y := 0; y < gb.Dy(); y++ {
+    for x := 0; x < gb.Dx(); x++ {
+      oc := color.RGBAModel.Convert(outimg.At(ob.Min.X + x, ob.Min.Y + y)).(color.RGBA)
+      gc := color.RGBAModel.Convert(goldenimg.At(gb.Min.X + x, gb.Min.Y + y)).(color.RGBA)
+      if channelDiff(oc.R, gc.R) > c.Tolerance || channelDiff(oc.G, gc.G) > c.Tolerance ||
+          channelDiff(oc.B, gc.B) > c.Tolerance || channelDiff(oc.A, gc.A) > c.Tolerance {
+        count++
+        diffimg.Set(x, y, color.RGBA{0xff, 0, 0, 0xff})
+      } else {
+        gray := color.GrayModel.Convert(gc).(color.Gray)
+        faded := 0xc0 + gray.Y / 4
+        diffimg.Set(x, y, color.RGBA{faded, faded, faded, 0xff})
+      }
+    }
+  }
+  if count <= c.MaxDiffPixels {
+    return nil
+  }
+  f, err := os.Create(diffpath)
+  if err != nil {
+    return fmt.Errorf("%d pixels differ; error creating diff image: %v", count, err)
+  }
+  defer f.Close()
+  if err := png.Encode(f, diffimg); err != nil {
+    return fmt.Errorf("%d pixels differ; error writing diff image: %v", count, err)
+  }
+  return fmt.Errorf("%d of %d pixels differ by more than %d; diff image is in %s",
+      count, gb.Dx() * gb.Dy(), c.Tolerance, diffpath)
+}
+
+// channelDiff returns the absolute difference between two color channel values.
+func channelDiff(a, b uint8) uint8 {
+  if a > b {
+    return a - b
+  }
+  return b - a
+}
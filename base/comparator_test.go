@@ -0,0 +1,117 @@
+package base_test
+
+import (
+  "bytes"
+  "image"
+  "image/color"
+  "image/png"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestCompareReportsTextDiff(t *testing.T) {
+  disableUpdate(t)
+  err := base.CompareOutToGolden("testdata/a.txt", "testdata/b.txt")
+  if err == nil {
+    t.Fatal("CompareOutToGolden: expected error about different contents")
+  }
+  if want := "-File B\n+File A\n"; !strings.Contains(err.Error(), want) {
+    t.Errorf("CompareOutToGolden error %q does not contain diff %q", err, want)
+  }
+}
+
+func TestHexReporter(t *testing.T) {
+  golden := []byte{0, 1, 2, 3}
+  out := []byte{0, 1, 0xff, 3}
+  err := base.BytesComparator{}.Compare("x.out", out, golden)
+  if err == nil {
+    t.Fatal("Compare: expected error about different contents")
+  }
+  if want := "+00000000  00 01 ff 03"; !strings.Contains(err.Error(), want) {
+    t.Errorf("Compare error %q does not contain hex diff %q", err, want)
+  }
+
+  report := base.HexReporter{MaxBytes: 2}.Report(out, golden)
+  if want := "first difference at offset 2"; !strings.Contains(report, want) {
+    t.Errorf("HexReporter summary %q does not contain %q", report, want)
+  }
+}
+
+func TestDiffImagePath(t *testing.T) {
+  if got, want := base.DiffImagePath("testdata/x.y/img.out"), "testdata/x.y/img.diff.png"; got != want {
+    t.Errorf("DiffImagePath: got %q, want %q", got, want)
+  }
+}
+
+// examplePng is used as the function under test that draws an image.
+func examplePng(size int, spot color.Color) []byte {
+  img := image.NewRGBA(image.Rect(0, 0, size, size))
+  for y := 0; y < size; y++ {
+    for x := 0; x < size; x++ {
+      img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 0x80, 0xff})
+    }
+  }
+  img.Set(1, 1, spot)
+  var buf bytes.Buffer
+  png.Encode(&buf, img)
+  return buf.Bytes()
+}
+
+func TestImageComparator(t *testing.T) {
+  disableUpdate(t)
+  dir := t.TempDir()
+  goldenpath := filepath.Join(dir, "img.golden")
+  if err := ioutil.WriteFile(goldenpath, examplePng(8, color.Black), 0644); err != nil {
+    t.Fatal(err)
+  }
+  run := func(spot color.Color, c base.ImageComparator) error {
+    r := base.NewTester("img")
+    r.BaseDir = dir
+    r.Comparator = c
+    r.Test = func(r *base.Tester) error {
+      _, err := r.OutW.Write(examplePng(8, spot))
+      return err
+    }
+    return base.RunOne(r)
+  }
+  diffpath := filepath.Join(dir, "img.diff.png")
+
+  if err := run(color.RGBA{2, 2, 2, 0xff}, base.ImageComparator{Tolerance: 2}); err != nil {
+    t.Errorf("Expected images within tolerance to match: %v", err)
+  }
+  if err := run(color.White, base.ImageComparator{MaxDiffPixels: 1}); err != nil {
+    t.Errorf("Expected images within MaxDiffPixels to match: %v", err)
+  }
+  err := run(color.White, base.ImageComparator{Tolerance: 2})
+  if err == nil {
+    t.Fatalf("Expected error for different images")
+  }
+  if want := "1 of 64 pixels differ"; !strings.Contains(err.Error(), want) {
+    t.Errorf("Image compare error %q does not contain %q", err, want)
+  }
+  f, err := os.Open(diffpath)
+  if err != nil {
+    t.Fatalf("Expected diff image: %v", err)
+  }
+  defer f.Close()
+  diffimg, err := png.Decode(f)
+  if err != nil {
+    t.Fatalf("Error decoding diff image: %v", err)
+  }
+  if got, want := color.RGBAModel.Convert(diffimg.At(1, 1)), (color.RGBA{0xff, 0, 0, 0xff}); got != want {
+    t.Errorf("Diff image pixel: got %v, want %v", got, want)
+  }
+}
+
+func TestImageComparatorSize(t *testing.T) {
+  err := base.ImageComparator{}.Compare(filepath.Join(t.TempDir(), "x.out"),
+      examplePng(4, color.Black), examplePng(8, color.Black))
+  if err == nil || !strings.Contains(err.Error(), "golden is 8x8, out is 4x4") {
+    t.Errorf("Expected image size error, got %v", err)
+  }
+}
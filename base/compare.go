@@ -1,24 +1,48 @@
 package base
 
 import (
-  "bytes"
+  "flag"
   "fmt"
   "io/ioutil"
 )
 
-// CompareOutToGolden reads the outfile and the goldenfile and compares them.
+// UpdateGolden is set by the -golden.update flag. When true,
+// CompareOutToGolden copies each output file to its golden file
+// instead of comparing them.
+var UpdateGolden = flag.Bool("golden.update", false, "update golden files from test output files")
+
+// CompareOutToGolden reads the outfile and the goldenfile and compares them
+// using DefaultComparator.
 // It returns an error if they are not the same.
+// If UpdateGolden is set, it instead writes the outfile content to the goldenfile.
 func CompareOutToGolden(outfilepath, goldenfilepath string) error {
+  return CompareOutToGoldenWith(outfilepath, goldenfilepath, nil)
+}
+
+// CompareOutToGoldenWith is like CompareOutToGolden but uses the given
+// Comparator, or DefaultComparator if that is nil.
+func CompareOutToGoldenWith(outfilepath, goldenfilepath string, comparator Comparator) error {
+  RecordUsed(outfilepath)
+  RecordUsed(goldenfilepath)
   outcontent, err := ioutil.ReadFile(outfilepath)
   if err != nil {
     return fmt.Errorf("error reading back output file %s: %v", outfilepath, err)
   }
+  if *UpdateGolden {
+    if err := ioutil.WriteFile(goldenfilepath, outcontent, 0644); err != nil {
+      return fmt.Errorf("error updating golden file %s: %v", goldenfilepath, err)
+    }
+    return nil
+  }
   goldencontent, err := ioutil.ReadFile(goldenfilepath)
   if err != nil {
     return fmt.Errorf("error reading golden file %s: %v", goldenfilepath, err)
   }
-  if !bytes.Equal(outcontent, goldencontent) {
-    return fmt.Errorf("outfile %s does not match golden file %s", outfilepath, goldenfilepath)
+  if comparator == nil {
+    comparator = DefaultComparator
+  }
+  if err := comparator.Compare(outfilepath, outcontent, goldencontent); err != nil {
+    return fmt.Errorf("outfile %s does not match golden file %s: %v", outfilepath, goldenfilepath, err)
   }
   return nil
 }
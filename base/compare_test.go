@@ -1,11 +1,21 @@
 package base_test
 
 import (
+  "io/ioutil"
+  "path/filepath"
   "testing"
 
   "github.com/jimmc/golden/base"
 )
 
+// disableUpdate turns off the -golden.update flag for the duration of
+// the test, for tests that expect a golden file mismatch.
+func disableUpdate(t *testing.T) {
+  update := *base.UpdateGolden
+  *base.UpdateGolden = false
+  t.Cleanup(func() { *base.UpdateGolden = update })
+}
+
 func TestCompareGood(t *testing.T) {
   err := base.CompareOutToGolden("testdata/a.txt", "testdata/a.txt")
   if err != nil {
@@ -14,6 +24,7 @@ func TestCompareGood(t *testing.T) {
 }
 
 func TestCompareBad(t *testing.T) {
+  disableUpdate(t)
   err := base.CompareOutToGolden("testdata/a.txt", "testdata/b.txt")
   if err == nil {
     t.Fatal("CompareOutToGolden: expected error about different contents")
@@ -28,8 +39,27 @@ func TestCompareNoOut(t *testing.T) {
 }
 
 func TestCompareNoGolden(t *testing.T) {
+  disableUpdate(t)
   err := base.CompareOutToGolden("testdata/a.txt", "no-such-file")
   if err == nil {
     t.Fatal("CompareOutToGolden: expected error about no golden file")
   }
 }
+
+func TestCompareUpdate(t *testing.T) {
+  dir := t.TempDir()
+  goldenpath := filepath.Join(dir, "a.golden")
+  if err := ioutil.WriteFile(goldenpath, []byte("old content\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  disableUpdate(t)
+  *base.UpdateGolden = true
+  err := base.CompareOutToGolden("testdata/a.txt", goldenpath)
+  *base.UpdateGolden = false
+  if err != nil {
+    t.Fatalf("CompareOutToGolden in update mode: %v", err)
+  }
+  if err := base.CompareOutToGolden("testdata/a.txt", goldenpath); err != nil {
+    t.Errorf("CompareOutToGolden after update: %v", err)
+  }
+}
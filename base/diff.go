@@ -0,0 +1,143 @@
+package base
+
+import (
+  "fmt"
+  "strings"
+)
+
+// DiffContext is the number of unchanged lines TextDiff shows around each change.
+var DiffContext = 2
+
+// maxDiffCells limits the size of the table TextDiff uses to find the
+// differences between the changed parts of two texts, which keeps that
+// table to a few megabytes.
+const maxDiffCells = 1000000
+
+// TextDiff returns a line-by-line diff from golden to out, or the empty
+// string if they are the same. Each group of changes starts with a line
+// giving the golden and out line numbers, followed by the lines only in
+// golden prefixed with "-", the lines only in out prefixed with "+",
+// and up to DiffContext unchanged lines on each side prefixed with " ".
+// Leading and trailing lines that are the same in both texts are not
+// included in the diff table, so large texts with few changes can be diffed.
+func TextDiff(golden, out string) string {
+  if golden == out {
+    return ""
+  }
+  a := splitLines(golden)
+  b := splitLines(out)
+  prefix := 0
+  for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+    prefix++
+  }
+  suffix := 0
+  for suffix < len(a) - prefix && suffix < len(b) - prefix &&
+      a[len(a) - 1 - suffix] == b[len(b) - 1 - suffix] {
+    suffix++
+  }
+  ma := a[prefix:len(a) - suffix]
+  mb := b[prefix:len(b) - suffix]
+  if len(ma) * len(mb) > maxDiffCells {
+    return fmt.Sprintf("(texts too large to diff: %d and %d changed lines)\n", len(ma), len(mb))
+  }
+
+  // lcs[i][j] is the length of the longest common subsequence of ma[i:] and mb[j:].
+  lcs := make([][]int, len(ma) + 1)
+  for i := range lcs {
+    lcs[i] = make([]int, len(mb) + 1)
+  }
+  for i := len(ma) - 1; i >= 0; i-- {
+    for j := len(mb) - 1; j >= 0; j-- {
+      if ma[i] == mb[j] {
+        lcs[i][j] = lcs[i+1][j+1] + 1
+      } else if lcs[i+1][j] >= lcs[i][j+1] {
+        lcs[i][j] = lcs[i+1][j]
+      } else {
+        lcs[i][j] = lcs[i][j+1]
+      }
+    }
+  }
+
+  type diffLine struct {
+    op byte
+    text string
+    aLine, bLine int
+  }
+  lines := make([]diffLine, 0, len(a) + len(b))
+  for k := 0; k < prefix; k++ {
+    lines = append(lines, diffLine{' ', a[k], k + 1, k + 1})
+  }
+  i, j := 0, 0
+  for i < len(ma) || j < len(mb) {
+    switch {
+    case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+      lines = append(lines, diffLine{' ', ma[i], prefix + i + 1, prefix + j + 1})
+      i++
+      j++
+    case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+      lines = append(lines, diffLine{'-', ma[i], prefix + i + 1, prefix + j + 1})
+      i++
+    default:
+      lines = append(lines, diffLine{'+', mb[j], prefix + i + 1, prefix + j + 1})
+      j++
+    }
+  }
+  for k := suffix; k > 0; k-- {
+    lines = append(lines, diffLine{' ', a[len(a) - k], len(a) - k + 1, len(b) - k + 1})
+  }
+
+  var sb strings.Builder
+  last := -1
+  for k := 0; k < len(lines); k++ {
+    if lines[k].op == ' ' {
+      continue
+    }
+    start := k - DiffContext
+    if start < 0 {
+      start = 0
+    }
+    if start <= last {
+      start = last + 1
+    } else {
+      fmt.Fprintf(&sb, "@@ golden line %d, out line %d @@\n", lines[start].aLine, lines[start].bLine)
+    }
+    end := k
+    for end + 1 < len(lines) {
+      // Extend through following changes that are within the context range.
+      next := end + 1
+      for next < len(lines) && lines[next].op == ' ' && next - end <= 2 * DiffContext {
+        next++
+      }
+      if next < len(lines) && lines[next].op != ' ' && next - end <= 2 * DiffContext + 1 {
+        end = next
+      } else {
+        break
+      }
+    }
+    stop := end + DiffContext
+    if stop >= len(lines) {
+      stop = len(lines) - 1
+    }
+    for m := start; m <= stop; m++ {
+      sb.WriteByte(lines[m].op)
+      sb.WriteString(lines[m].text)
+      sb.WriteString("\n")
+    }
+    last = stop
+    k = stop
+  }
+  return sb.String()
+}
+
+// splitLines splits s into lines, noting a missing final newline.
+func splitLines(s string) []string {
+  if s == "" {
+    return []string{}
+  }
+  lines := strings.Split(s, "\n")
+  if lines[len(lines) - 1] == "" {
+    return lines[:len(lines) - 1]
+  }
+  lines[len(lines) - 1] += " (no newline at end)"
+  return lines
+}
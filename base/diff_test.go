@@ -0,0 +1,56 @@
+package base_test
+
+import (
+  "fmt"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestTextDiff(t *testing.T) {
+  tests := []struct{
+    golden, out, want string
+  }{
+    {"a\nb\n", "a\nb\n", ""},
+    {"a\nb\nc\n", "a\nx\nc\n", "@@ golden line 1, out line 1 @@\n a\n-b\n+x\n c\n"},
+    {"", "a\n", "@@ golden line 1, out line 1 @@\n+a\n"},
+    {"a\n", "a", "@@ golden line 1, out line 1 @@\n-a\n+a (no newline at end)\n"},
+    {"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\n2\n3\n4\n5\n6\n7\n8\nX\n",
+        "@@ golden line 7, out line 7 @@\n 7\n 8\n-9\n+X\n"},
+    {"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "X\n2\n3\n4\n5\n6\n7\n8\n9\nY\n",
+        "@@ golden line 1, out line 1 @@\n-1\n+X\n 2\n 3\n" +
+        "@@ golden line 8, out line 8 @@\n 8\n 9\n-10\n+Y\n"},
+    {"1\n2\n3\n4\n5\n", "X\n2\n3\n4\nY\n",
+        "@@ golden line 1, out line 1 @@\n-1\n+X\n 2\n 3\n 4\n-5\n+Y\n"},
+  }
+  for _, tc := range tests {
+    if got := base.TextDiff(tc.golden, tc.out); got != tc.want {
+      t.Errorf("TextDiff(%q, %q):\ngot  %q\nwant %q", tc.golden, tc.out, got, tc.want)
+    }
+  }
+}
+
+// numberedLines returns n lines, each holding its line number plus offset.
+func numberedLines(n, offset int) string {
+  var sb strings.Builder
+  for i := 1; i <= n; i++ {
+    fmt.Fprintf(&sb, "%d\n", i + offset)
+  }
+  return sb.String()
+}
+
+func TestTextDiffLarge(t *testing.T) {
+  golden := numberedLines(5000, 0)
+  out := strings.Replace(golden, "\n2500\n", "\nchanged\n", 1)
+  want := "@@ golden line 2498, out line 2498 @@\n 2498\n 2499\n-2500\n+changed\n 2501\n 2502\n"
+  if got := base.TextDiff(golden, out); got != want {
+    t.Errorf("TextDiff of large texts with one change:\ngot  %q\nwant %q", got, want)
+  }
+
+  out = numberedLines(5000, 10000)
+  want = "(texts too large to diff: 5000 and 5000 changed lines)\n"
+  if got := base.TextDiff(golden, out); got != want {
+    t.Errorf("TextDiff of large different texts: got %q, want %q", got, want)
+  }
+}
@@ -0,0 +1,148 @@
+package base
+
+import (
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+)
+
+// CompareDirToGolden compares all of the files in outdir and its
+// subdirectories to the files in goldendir using DefaultComparator.
+// See CompareDirToGoldenWith.
+func CompareDirToGolden(outdir, goldendir string, compareModes bool) error {
+  return CompareDirToGoldenWith(outdir, goldendir, compareModes, nil)
+}
+
+// CompareDirToGoldenWith compares all of the files in outdir and its
+// subdirectories to the files in goldendir. It returns an error that lists
+// the files that were added (in outdir but not in goldendir), the files
+// that are missing (in goldendir but not in outdir), and the files that
+// changed, with the comparator's description of the differences for each
+// changed file. If comparator is nil, DefaultComparator is used.
+// If compareModes is true, it also reports files that are executable in
+// one directory but not in the other. Other permission bits are ignored,
+// since they depend on the umask and on how the files were checked out.
+// Empty directories are ignored.
+// If UpdateGolden is set, it instead replaces goldendir with a copy of outdir.
+func CompareDirToGoldenWith(outdir, goldendir string, compareModes bool, comparator Comparator) error {
+  if comparator == nil {
+    comparator = DefaultComparator
+  }
+  RecordUsed(outdir)
+  RecordUsed(goldendir)
+  outfiles, err := listFiles(outdir)
+  if err != nil {
+    return fmt.Errorf("error reading output directory %s: %v", outdir, err)
+  }
+  if *UpdateGolden {
+    return syncDir(outdir, goldendir, outfiles)
+  }
+  goldenfiles, err := listFiles(goldendir)
+  if err != nil {
+    return fmt.Errorf("error reading golden directory %s: %v", goldendir, err)
+  }
+
+  problems := make([]string, 0)
+  for _, name := range sortedKeys(outfiles) {
+    if _, ok := goldenfiles[name]; !ok {
+      problems = append(problems, "added: " + name)
+    }
+  }
+  for _, name := range sortedKeys(goldenfiles) {
+    outinfo, ok := outfiles[name]
+    if !ok {
+      problems = append(problems, "missing: " + name)
+      continue
+    }
+    goldeninfo := goldenfiles[name]
+    outpath := filepath.Join(outdir, name)
+    outcontent, err := ioutil.ReadFile(outpath)
+    if err != nil {
+      return err
+    }
+    goldencontent, err := ioutil.ReadFile(filepath.Join(goldendir, name))
+    if err != nil {
+      return err
+    }
+    if err := comparator.Compare(outpath, outcontent, goldencontent); err != nil {
+      problems = append(problems, "changed: " + name + "\n" + err.Error())
+    }
+    if compareModes && isExecutable(outinfo) != isExecutable(goldeninfo) {
+      problems = append(problems, fmt.Sprintf("mode changed: %s: golden %v, out %v",
+          name, goldeninfo.Mode().Perm(), outinfo.Mode().Perm()))
+    }
+  }
+  if len(problems) > 0 {
+    return fmt.Errorf("output directory %s does not match golden directory %s:\n%s",
+        outdir, goldendir, strings.Join(problems, "\n"))
+  }
+  return nil
+}
+
+// listFiles returns the regular files in dir and its subdirectories,
+// keyed by their slash-separated paths relative to dir.
+func listFiles(dir string) (map[string]os.FileInfo, error) {
+  files := make(map[string]os.FileInfo)
+  err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+    if err != nil {
+      return err
+    }
+    if info.IsDir() {
+      return nil
+    }
+    rel, err := filepath.Rel(dir, path)
+    if err != nil {
+      return err
+    }
+    files[filepath.ToSlash(rel)] = info
+    return nil
+  })
+  return files, err
+}
+
+// isExecutable returns true if any of the execute bits of the file are set.
+func isExecutable(info os.FileInfo) bool {
+  return info.Mode().Perm()&0111 != 0
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m map[string]os.FileInfo) []string {
+  keys := make([]string, 0, len(m))
+  for key := range m {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// syncDir replaces goldendir with a copy of the given files from outdir,
+// keeping their permission bits.
+func syncDir(outdir, goldendir string, outfiles map[string]os.FileInfo) error {
+  if err := os.RemoveAll(goldendir); err != nil {
+    return fmt.Errorf("error removing golden directory %s: %v", goldendir, err)
+  }
+  if err := os.MkdirAll(goldendir, 0755); err != nil {
+    return err
+  }
+  for _, name := range sortedKeys(outfiles) {
+    content, err := ioutil.ReadFile(filepath.Join(outdir, name))
+    if err != nil {
+      return err
+    }
+    goldenpath := filepath.Join(goldendir, filepath.FromSlash(name))
+    if err := os.MkdirAll(filepath.Dir(goldenpath), 0755); err != nil {
+      return err
+    }
+    if err := ioutil.WriteFile(goldenpath, content, outfiles[name].Mode().Perm()); err != nil {
+      return fmt.Errorf("error updating golden file %s: %v", goldenpath, err)
+    }
+    // WriteFile applies the umask, so set the mode explicitly.
+    if err := os.Chmod(goldenpath, outfiles[name].Mode().Perm()); err != nil {
+      return err
+    }
+  }
+  return nil
+}
@@ -0,0 +1,153 @@
+package base_test
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+// writeTree is used as the function under test that writes a directory tree.
+func writeTree(dir string, files map[string]string) error {
+  for name, content := range files {
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+      return err
+    }
+    mode := os.FileMode(0644)
+    if strings.HasSuffix(name, ".sh") {
+      mode = 0755
+    }
+    if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+      return err
+    }
+    if err := os.Chmod(path, mode); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+var treeFiles = map[string]string{
+  "a.txt": "File A\n",
+  "sub/b.txt": "File B\nline 2\n",
+  "run.sh": "#!/bin/sh\necho hi\n",
+}
+
+func TestDirOutput(t *testing.T) {
+  goldendir := filepath.Join(t.TempDir(), "tree.golden")
+  if err := writeTree(goldendir, treeFiles); err != nil {
+    t.Fatal(err)
+  }
+  r := base.NewTester("tree")
+  r.GoldenPath = goldendir
+  r.DirOutput = true
+  r.CompareModes = true
+  r.Test = func(r *base.Tester) error {
+    return writeTree(r.OutDir, treeFiles)
+  }
+  if got, want := r.OutDirPath(), "testdata/tree.outdir"; got != want {
+    t.Errorf("OutDirPath: got %q, want %q", got, want)
+  }
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestCompareDirMismatch(t *testing.T) {
+  disableUpdate(t)
+  goldendir := filepath.Join(t.TempDir(), "tree.golden")
+  if err := writeTree(goldendir, treeFiles); err != nil {
+    t.Fatal(err)
+  }
+  outdir := t.TempDir()
+  err := writeTree(outdir, map[string]string{
+    "a.txt": "File A changed\n",
+    "new.txt": "new\n",
+    "run.sh": "#!/bin/sh\necho hi\n",
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+  os.Chmod(filepath.Join(outdir, "run.sh"), 0644)
+  err = base.CompareDirToGolden(outdir, goldendir, true)
+  if err == nil {
+    t.Fatalf("Expected error for mismatched directories")
+  }
+  for _, want := range []string{
+    "added: new.txt",
+    "missing: sub/b.txt",
+    "changed: a.txt\ndifferences (-golden +out):\n@@ golden line 1, out line 1 @@\n-File A\n+File A changed\n",
+    "mode changed: run.sh: golden -rwxr-xr-x, out -rw-r--r--",
+  } {
+    if !strings.Contains(err.Error(), want) {
+      t.Errorf("CompareDirToGolden error %q does not contain %q", err, want)
+    }
+  }
+}
+
+func TestCompareDirIgnoresGroupWrite(t *testing.T) {
+  disableUpdate(t)
+  goldendir := t.TempDir()
+  outdir := t.TempDir()
+  for _, dir := range []string{goldendir, outdir} {
+    if err := writeTree(dir, treeFiles); err != nil {
+      t.Fatal(err)
+    }
+  }
+  os.Chmod(filepath.Join(outdir, "a.txt"), 0664)
+  os.Chmod(filepath.Join(outdir, "run.sh"), 0775)
+  if err := base.CompareDirToGolden(outdir, goldendir, true); err != nil {
+    t.Errorf("CompareDirToGolden with group write bits: %v", err)
+  }
+}
+
+// matchAll is a Comparator that treats all contents as matching.
+type matchAll struct{}
+
+func (matchAll) Compare(outfilepath string, out, golden []byte) error {
+  return nil
+}
+
+func TestCompareDirWithComparator(t *testing.T) {
+  disableUpdate(t)
+  outdir := t.TempDir()
+  goldendir := t.TempDir()
+  if err := writeTree(goldendir, map[string]string{"a.bin": "\x00\x01\x02"}); err != nil {
+    t.Fatal(err)
+  }
+  if err := writeTree(outdir, map[string]string{"a.bin": "\x00\x01\xff"}); err != nil {
+    t.Fatal(err)
+  }
+  err := base.CompareDirToGolden(outdir, goldendir, false)
+  if want := "+00000000  00 01 ff"; err == nil || !strings.Contains(err.Error(), want) {
+    t.Errorf("CompareDirToGolden error %v does not contain hex diff %q", err, want)
+  }
+  if err := base.CompareDirToGoldenWith(outdir, goldendir, false, matchAll{}); err != nil {
+    t.Errorf("CompareDirToGoldenWith: %v", err)
+  }
+}
+
+func TestCompareDirUpdate(t *testing.T) {
+  outdir := t.TempDir()
+  goldendir := filepath.Join(t.TempDir(), "x.golden")
+  if err := writeTree(goldendir, map[string]string{"old.txt": "old\n"}); err != nil {
+    t.Fatal(err)
+  }
+  if err := writeTree(outdir, treeFiles); err != nil {
+    t.Fatal(err)
+  }
+  disableUpdate(t)
+  *base.UpdateGolden = true
+  err := base.CompareDirToGolden(outdir, goldendir, true)
+  *base.UpdateGolden = false
+  if err != nil {
+    t.Fatalf("CompareDirToGolden in update mode: %v", err)
+  }
+  if err := base.CompareDirToGolden(outdir, goldendir, true); err != nil {
+    t.Errorf("CompareDirToGolden after update: %v", err)
+  }
+}
@@ -0,0 +1,44 @@
+package base
+
+import (
+  "io"
+)
+
+// FixtureTester is a Tester whose test function receives a typed fixture
+// value, such as a database, along with the output writer, so that the test
+// function does not need to capture the tester to get at the fixture.
+// Testers that embed a FixtureTester and manage their own fixture,
+// such as db.Tester, call ActWith from their Act method.
+type FixtureTester[F any] struct {
+  Tester
+
+  // The fixture passed to TestFunc by Act.
+  Fixture F
+  // Function to run the test; if not set, Act calls the untyped Test function.
+  TestFunc func(F, io.Writer) error
+}
+
+// NewFixtureTester creates a new FixtureTester instance that will call
+// the test function with the given fixture.
+func NewFixtureTester[F any](basename string, fixture F, test func(F, io.Writer) error) *FixtureTester[F] {
+  r := &FixtureTester[F]{
+    Fixture: fixture,
+    TestFunc: test,
+  }
+  r.BaseName = basename
+  return r
+}
+
+// Act calls TestFunc with Fixture.
+func (r *FixtureTester[F]) Act() error {
+  return r.ActWith(r.Fixture)
+}
+
+// ActWith calls TestFunc with the given fixture and the output writer.
+// If TestFunc is not set, it calls the untyped Test function instead.
+func (r *FixtureTester[F]) ActWith(fixture F) error {
+  if r.TestFunc == nil {
+    return r.Tester.Act()
+  }
+  return r.TestFunc(fixture, r.OutW)
+}
@@ -0,0 +1,49 @@
+package base_test
+
+import (
+  "fmt"
+  "io"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestFixtureTester(t *testing.T) {
+  r := base.NewFixtureTester("run-example", "run", func(s string, w io.Writer) error {
+    _, err := io.WriteString(w, example(s))
+    return err
+  })
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestFixtureTesterFallback(t *testing.T) {
+  r := &base.FixtureTester[int]{}
+  r.BaseName = "run-example"
+  r.Test = func(r *base.Tester) error {
+    _, err := io.WriteString(r.OutW, example("run"))
+    return err
+  }
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestFixtureTesterActWith(t *testing.T) {
+  r := base.NewFixtureTester("example", 0, func(n int, w io.Writer) error {
+    if n != 42 {
+      return fmt.Errorf("got fixture %d, want 42", n)
+    }
+    return nil
+  })
+  if err := r.Arrange(); err != nil {
+    t.Fatalf("Error in Arrange: %v", err)
+  }
+  if err := r.ActWith(42); err != nil {
+    t.Errorf("Error in ActWith: %v", err)
+  }
+  if err := r.Act(); err == nil {
+    t.Errorf("Expected error from Act with zero fixture")
+  }
+}
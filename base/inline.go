@@ -0,0 +1,155 @@
+package base
+
+import (
+  "bytes"
+  "fmt"
+  "go/ast"
+  "go/parser"
+  "go/printer"
+  "go/token"
+  "io/ioutil"
+  "os"
+  "runtime"
+  "strconv"
+  "strings"
+  "sync"
+  "testing"
+  "unicode/utf8"
+)
+
+// inlineFuncName is the name of the function whose literal argument
+// RewriteInline updates.
+const inlineFuncName = "AssertInline"
+
+// inlineEdit records a rewrite that changed the number of lines in a file.
+type inlineEdit struct {
+  line int
+  delta int
+}
+
+var (
+  inlineMu sync.Mutex
+  // Edits made so far to each file, so that the line numbers reported by
+  // runtime.Caller, which refer to the source as compiled, can be adjusted.
+  inlineEdits = make(map[string][]inlineEdit)
+)
+
+// AssertInline compares got to want, which should be a string literal
+// in the calling test source, and reports any difference with t.Errorf.
+// If UpdateGolden is set and they differ, it instead rewrites the want
+// literal in the calling source file to be the value of got.
+// This is for small golden values that are easier to review inline
+// than in a separate golden file.
+func AssertInline(t *testing.T, got, want string) {
+  t.Helper()
+  if got == want {
+    return
+  }
+  if *UpdateGolden {
+    _, filename, line, ok := runtime.Caller(1)
+    if !ok {
+      t.Errorf("AssertInline: can not determine caller to update")
+      return
+    }
+    if err := RewriteInline(filename, line, got); err != nil {
+      t.Errorf("AssertInline: %v", err)
+    }
+    return
+  }
+  t.Errorf("AssertInline mismatch (-want +got):\n%s", TextDiff(want, got))
+}
+
+// RewriteInline replaces the last argument of the call to AssertInline
+// at the given line of the Go source file with a string literal for value.
+// The line is a line number in the file as it was before any earlier calls
+// to RewriteInline for the file. Only the literal is changed, so the rest
+// of the file keeps its formatting.
+func RewriteInline(filename string, line int, value string) error {
+  inlineMu.Lock()
+  defer inlineMu.Unlock()
+
+  adjusted := line
+  for _, edit := range inlineEdits[filename] {
+    if edit.line < line {
+      adjusted += edit.delta
+    }
+  }
+
+  info, err := os.Stat(filename)
+  if err != nil {
+    return err
+  }
+  src, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return err
+  }
+  fset := token.NewFileSet()
+  f, err := parser.ParseFile(fset, filename, src, 0)
+  if err != nil {
+    return err
+  }
+  var lit *ast.BasicLit
+  ast.Inspect(f, func(n ast.Node) bool {
+    call, ok := n.(*ast.CallExpr)
+    if !ok || lit != nil {
+      return lit == nil
+    }
+    if fset.Position(call.Pos()).Line > adjusted || fset.Position(call.End()).Line < adjusted {
+      return true
+    }
+    if !isInlineFunc(call.Fun) || len(call.Args) == 0 {
+      return true
+    }
+    arg, ok := call.Args[len(call.Args) - 1].(*ast.BasicLit)
+    if ok && arg.Kind == token.STRING {
+      lit = arg
+    }
+    return lit == nil
+  })
+  if lit == nil {
+    return fmt.Errorf("no call to %s with a string literal argument at %s:%d",
+        inlineFuncName, filename, adjusted)
+  }
+
+  var buf bytes.Buffer
+  newLit := &ast.BasicLit{Kind: token.STRING, Value: goStringLiteral(value)}
+  if err := printer.Fprint(&buf, token.NewFileSet(), newLit); err != nil {
+    return err
+  }
+  start := fset.Position(lit.Pos()).Offset
+  end := fset.Position(lit.End()).Offset
+  updated := make([]byte, 0, len(src) + buf.Len())
+  updated = append(updated, src[:start]...)
+  updated = append(updated, buf.Bytes()...)
+  updated = append(updated, src[end:]...)
+  if err := ioutil.WriteFile(filename, updated, info.Mode().Perm()); err != nil {
+    return err
+  }
+
+  delta := strings.Count(buf.String(), "\n") - strings.Count(lit.Value, "\n")
+  if delta != 0 {
+    inlineEdits[filename] = append(inlineEdits[filename], inlineEdit{line: line, delta: delta})
+  }
+  return nil
+}
+
+// isInlineFunc returns true if the expression names AssertInline,
+// either directly or qualified by a package name.
+func isInlineFunc(fun ast.Expr) bool {
+  switch f := fun.(type) {
+  case *ast.Ident:
+    return f.Name == inlineFuncName
+  case *ast.SelectorExpr:
+    return f.Sel.Name == inlineFuncName
+  }
+  return false
+}
+
+// goStringLiteral returns a Go string literal for s, using a raw string
+// literal for multi-line text where possible.
+func goStringLiteral(s string) string {
+  if strings.Contains(s, "\n") && utf8.ValidString(s) && !strings.ContainsAny(s, "`\r\x00") {
+    return "`" + s + "`"
+  }
+  return strconv.Quote(s)
+}
@@ -0,0 +1,64 @@
+package base_test
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestAssertInline(t *testing.T) {
+  base.AssertInline(t, example("inline"), "This is the output of example(\"inline\").\n")
+  base.AssertInline(t, example("inline")+example("two"), `This is the output of example("inline").
+This is the output of example("two").
+`)
+}
+
+const inlineSource = `package x_test
+
+func TestX(t *testing.T) {
+  base.AssertInline(t, f(), "old")
+  AssertInline(t, g(),
+    "old2")
+  base.AssertInline(t, h(), "old3")
+}
+`
+
+func TestRewriteInline(t *testing.T) {
+  filename := filepath.Join(t.TempDir(), "x_test.go")
+  if err := ioutil.WriteFile(filename, []byte(inlineSource), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := base.RewriteInline(filename, 4, "new\nlines\n"); err != nil {
+    t.Fatalf("RewriteInline line 4: %v", err)
+  }
+  // Line numbers refer to the original source, before the first rewrite.
+  if err := base.RewriteInline(filename, 6, "new \"2\""); err != nil {
+    t.Fatalf("RewriteInline line 6: %v", err)
+  }
+  if err := base.RewriteInline(filename, 7, "new3"); err != nil {
+    t.Fatalf("RewriteInline line 7: %v", err)
+  }
+  got, err := ioutil.ReadFile(filename)
+  if err != nil {
+    t.Fatal(err)
+  }
+  want := "package x_test\n\nfunc TestX(t *testing.T) {\n" +
+      "  base.AssertInline(t, f(), `new\nlines\n`)\n" +
+      "  AssertInline(t, g(),\n    \"new \\\"2\\\"\")\n" +
+      "  base.AssertInline(t, h(), \"new3\")\n}\n"
+  if string(got) != want {
+    t.Errorf("Rewritten source:\ngot  %q\nwant %q", got, want)
+  }
+}
+
+func TestRewriteInlineNoCall(t *testing.T) {
+  filename := filepath.Join(t.TempDir(), "x_test.go")
+  if err := ioutil.WriteFile(filename, []byte(inlineSource), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := base.RewriteInline(filename, 3, "x"); err == nil {
+    t.Errorf("Expected error for line with no AssertInline call")
+  }
+}
@@ -0,0 +1,12 @@
+package base_test
+
+import (
+  "os"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestMain(m *testing.M) {
+  os.Exit(base.Main(m))
+}
@@ -0,0 +1,87 @@
+package base
+
+import (
+  "sync"
+)
+
+// Observer is notified before and after each phase of a test run by
+// RunTest or RunOne. Observers can be added for all tests with AddObserver
+// or for one Tester in its Observers field.
+type Observer interface {
+  // BeforePhase is called before the phase is run.
+  BeforePhase(r Runner, phase Phase)
+
+  // AfterPhase is called after the phase is run, with the error returned
+  // by the phase, or nil if it succeeded. If the phase succeeded and
+  // AfterPhase returns an error, the phase fails with that error.
+  AfterPhase(r Runner, phase Phase, err error) error
+}
+
+// ObserverFuncs is an Observer that calls the given functions.
+// Either function may be nil.
+type ObserverFuncs struct {
+  Before func(r Runner, phase Phase)
+  After func(r Runner, phase Phase, err error) error
+}
+
+// BeforePhase calls the Before function if it is set.
+func (o ObserverFuncs) BeforePhase(r Runner, phase Phase) {
+  if o.Before != nil {
+    o.Before(r, phase)
+  }
+}
+
+// AfterPhase calls the After function if it is set.
+func (o ObserverFuncs) AfterPhase(r Runner, phase Phase, err error) error {
+  if o.After != nil {
+    return o.After(r, phase, err)
+  }
+  return nil
+}
+
+// globalObserver is an Observer added by AddObserver.
+type globalObserver struct {
+  id int
+  observer Observer
+}
+
+var (
+  observerMu sync.Mutex
+  observerID int
+  globalObservers []globalObserver
+)
+
+// AddObserver adds an Observer that is called for every test run by
+// RunTest or RunOne. It returns a function that removes the Observer.
+func AddObserver(o Observer) (remove func()) {
+  observerMu.Lock()
+  defer observerMu.Unlock()
+  observerID++
+  id := observerID
+  globalObservers = append(globalObservers, globalObserver{id, o})
+  return func() {
+    observerMu.Lock()
+    defer observerMu.Unlock()
+    for i, g := range globalObservers {
+      if g.id == id {
+        globalObservers = append(globalObservers[:i:i], globalObservers[i+1:]...)
+        return
+      }
+    }
+  }
+}
+
+// observersFor returns the global observers followed by the runner's own
+// observers, if it has a PhaseObservers method.
+func observersFor(r Runner) []Observer {
+  observerMu.Lock()
+  observers := make([]Observer, 0, len(globalObservers))
+  for _, g := range globalObservers {
+    observers = append(observers, g.observer)
+  }
+  observerMu.Unlock()
+  if o, ok := r.(interface{ PhaseObservers() []Observer }); ok {
+    observers = append(observers, o.PhaseObservers()...)
+  }
+  return observers
+}
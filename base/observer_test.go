@@ -0,0 +1,76 @@
+package base_test
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestObservers(t *testing.T) {
+  events := make([]string, 0)
+  record := func(prefix string) base.Observer {
+    return base.ObserverFuncs{
+      Before: func(r base.Runner, phase base.Phase) {
+        events = append(events, fmt.Sprintf("%s before %s", prefix, phase))
+      },
+      After: func(r base.Runner, phase base.Phase, err error) error {
+        events = append(events, fmt.Sprintf("%s after %s %v", prefix, phase, err))
+        return nil
+      },
+    }
+  }
+  remove := base.AddObserver(record("global"))
+  r := base.NewTester("run-example")
+  r.Test = func(r *base.Tester) error {
+    _, err := io.WriteString(r.OutW, example("run"))
+    return err
+  }
+  r.Observers = []base.Observer{record("local")}
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in Run: %v", err)
+  }
+  remove()
+  if err := base.RunTest(r); err != nil {
+    t.Fatalf("Error in Run: %v", err)
+  }
+
+  got := strings.Join(events, "\n")
+  want := strings.Join([]string{
+    "global before Init", "local before Init", "global after Init <nil>", "local after Init <nil>",
+    "global before Arrange", "local before Arrange", "global after Arrange <nil>", "local after Arrange <nil>",
+    "global before Act", "local before Act", "global after Act <nil>", "local after Act <nil>",
+    "global before Assert", "local before Assert", "global after Assert <nil>", "local after Assert <nil>",
+    "global before Close", "local before Close", "global after Close <nil>", "local after Close <nil>",
+    "local before Arrange", "local after Arrange <nil>",
+    "local before Act", "local after Act <nil>",
+    "local before Assert", "local after Assert <nil>",
+  }, "\n")
+  if got != want {
+    t.Errorf("events:\n%s", base.TextDiff(want, got))
+  }
+}
+
+func TestObserverFailsPhase(t *testing.T) {
+  budgetErr := errors.New("over budget")
+  r := base.NewTester("run-example")
+  r.Test = func(r *base.Tester) error {
+    _, err := io.WriteString(r.OutW, example("run"))
+    return err
+  }
+  r.Observers = []base.Observer{base.ObserverFuncs{
+    After: func(r base.Runner, phase base.Phase, err error) error {
+      if phase == base.PhaseAct {
+        return budgetErr
+      }
+      return nil
+    },
+  }}
+  res := base.RunTestResult(r)
+  if !errors.Is(res.Err, budgetErr) || res.Phase != base.PhaseAct {
+    t.Errorf("expected Act to fail with %v, got %v in %s", budgetErr, res.Err, res.Phase)
+  }
+}
@@ -0,0 +1,194 @@
+package base
+
+import (
+  "encoding/json"
+  "encoding/xml"
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+  "sync"
+  "time"
+)
+
+// ReportPath is set by the -golden.report flag. When set, Main writes a
+// report of all of the golden comparisons to this file, as JUnit XML if
+// the file name ends with ".xml" and as JSON otherwise.
+var ReportPath = flag.String("golden.report", "", "write a JSON or JUnit XML (.xml) report of golden results to this file")
+
+// ReportDiffLines is the maximum number of lines of the failure message
+// included in the Diff of a ReportRecord.
+var ReportDiffLines = 20
+
+// ReportRecord is the result of one golden comparison, or of a test that
+// failed before making any comparisons.
+type ReportRecord struct {
+  // The name of the test, from the runner's TestName method if it has one.
+  Test string `json:"test"`
+  // The last phase the test reached.
+  Phase Phase `json:"phase"`
+  OutPath string `json:"out,omitempty"`
+  GoldenPath string `json:"golden,omitempty"`
+  Passed bool `json:"passed"`
+  // A summary of the differences or other failure, limited to ReportDiffLines.
+  Diff string `json:"diff,omitempty"`
+  // The time taken by the comparison, or by all of the test phases if
+  // there were no comparisons.
+  Duration time.Duration `json:"-"`
+  Seconds float64 `json:"seconds"`
+}
+
+var (
+  reportMu sync.Mutex
+  reportRecords = make([]*ReportRecord, 0)
+)
+
+// ReportRecords returns the records added so far by RunTest and RunOne.
+func ReportRecords() []*ReportRecord {
+  reportMu.Lock()
+  defer reportMu.Unlock()
+  return append([]*ReportRecord{}, reportRecords...)
+}
+
+// addReportRecords adds a record for each comparison made by the runner,
+// if it has a Comparisons method, and for a failure not caused by a
+// comparison. It does nothing unless the -golden.report flag is set.
+func addReportRecords(r Runner, res *Result) {
+  if *ReportPath == "" {
+    return
+  }
+  phase, err := res.Phase, res.Err
+  name := fmt.Sprintf("%T", r)
+  if n, ok := r.(interface{ TestName() string }); ok {
+    name = n.TestName()
+  }
+  var comparisons []Comparison
+  if c, ok := r.(interface{ Comparisons() []Comparison }); ok && phase != PhaseInit && phase != PhaseArrange {
+    comparisons = c.Comparisons()
+  }
+  records := make([]*ReportRecord, 0, len(comparisons) + 1)
+  failedComparison := false
+  for _, c := range comparisons {
+    records = append(records, newReportRecord(name, phase, c.OutPath, c.GoldenPath, c.Err, c.Duration))
+    failedComparison = failedComparison || c.Err != nil
+  }
+  if len(comparisons) == 0 || (err != nil && !failedComparison) {
+    records = append(records, newReportRecord(name, phase, "", "", err, res.Duration()))
+  }
+  reportMu.Lock()
+  defer reportMu.Unlock()
+  reportRecords = append(reportRecords, records...)
+}
+
+// newReportRecord creates a ReportRecord, summarizing err as the Diff.
+func newReportRecord(name string, phase Phase, outpath, goldenpath string,
+    err error, d time.Duration) *ReportRecord {
+  record := &ReportRecord{
+    Test: name,
+    Phase: phase,
+    OutPath: outpath,
+    GoldenPath: goldenpath,
+    Passed: err == nil,
+    Duration: d,
+    Seconds: d.Seconds(),
+  }
+  if err != nil {
+    lines := strings.Split(strings.TrimSuffix(err.Error(), "\n"), "\n")
+    if len(lines) > ReportDiffLines {
+      lines = append(lines[:ReportDiffLines], fmt.Sprintf("... (%d more lines)", len(lines) - ReportDiffLines))
+    }
+    record.Diff = strings.Join(lines, "\n")
+  }
+  return record
+}
+
+// WriteJSONReport writes the records as an indented JSON array.
+func WriteJSONReport(w io.Writer, records []*ReportRecord) error {
+  enc := json.NewEncoder(w)
+  enc.SetIndent("", "  ")
+  return enc.Encode(records)
+}
+
+// junitTestSuite and junitTestCase are the JUnit XML elements
+// written by WriteJUnitReport.
+type junitTestSuite struct {
+  XMLName xml.Name `xml:"testsuite"`
+  Name string `xml:"name,attr"`
+  Tests int `xml:"tests,attr"`
+  Failures int `xml:"failures,attr"`
+  Time string `xml:"time,attr"`
+  TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+  Name string `xml:"name,attr"`
+  ClassName string `xml:"classname,attr"`
+  Time string `xml:"time,attr"`
+  Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+  Message string `xml:"message,attr"`
+  Text string `xml:",chardata"`
+}
+
+// WriteJUnitReport writes the records as a JUnit XML test suite with
+// one test case per record.
+func WriteJUnitReport(w io.Writer, records []*ReportRecord) error {
+  suite := junitTestSuite{Name: "golden", Tests: len(records)}
+  var total time.Duration
+  for _, record := range records {
+    name := record.Test
+    if record.GoldenPath != "" {
+      name += " " + record.GoldenPath
+    }
+    tc := junitTestCase{
+      Name: name,
+      ClassName: record.Test,
+      Time: fmt.Sprintf("%.3f", record.Duration.Seconds()),
+    }
+    if !record.Passed {
+      suite.Failures++
+      tc.Failure = &junitFailure{
+        Message: fmt.Sprintf("failed in %s", record.Phase),
+        Text: record.Diff,
+      }
+    }
+    total += record.Duration
+    suite.TestCases = append(suite.TestCases, tc)
+  }
+  suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+  if _, err := io.WriteString(w, xml.Header); err != nil {
+    return err
+  }
+  enc := xml.NewEncoder(w)
+  enc.Indent("", "  ")
+  if err := enc.Encode(suite); err != nil {
+    return err
+  }
+  _, err := io.WriteString(w, "\n")
+  return err
+}
+
+// WriteReportFile writes the records added so far to the named file,
+// as JUnit XML if the name ends with ".xml" and as JSON otherwise.
+func WriteReportFile(filename string) error {
+  f, err := os.Create(filename)
+  if err != nil {
+    return fmt.Errorf("error creating report file %s: %v", filename, err)
+  }
+  if filepath.Ext(filename) == ".xml" {
+    err = WriteJUnitReport(f, ReportRecords())
+  } else {
+    err = WriteJSONReport(f, ReportRecords())
+  }
+  if cerr := f.Close(); err == nil {
+    err = cerr
+  }
+  if err != nil {
+    return fmt.Errorf("error writing report file %s: %v", filename, err)
+  }
+  return nil
+}
@@ -0,0 +1,127 @@
+package base_test
+
+import (
+  "bytes"
+  "encoding/json"
+  "io"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+// reportRecordsFor returns the report records for the named test,
+// skipping the first skip records.
+func reportRecordsFor(name string, skip int) []*base.ReportRecord {
+  records := make([]*base.ReportRecord, 0)
+  for _, record := range base.ReportRecords()[skip:] {
+    if record.Test == name {
+      records = append(records, record)
+    }
+  }
+  return records
+}
+
+// withReport sets the -golden.report flag for the duration of the test,
+// so that test results are recorded.
+func withReport(t *testing.T) {
+  saved := *base.ReportPath
+  *base.ReportPath = filepath.Join(t.TempDir(), "report.json")
+  t.Cleanup(func() { *base.ReportPath = saved })
+}
+
+func TestReportNotRecordedWithoutFlag(t *testing.T) {
+  if *base.ReportPath != "" {
+    t.Skip("-golden.report is set")
+  }
+  skip := len(base.ReportRecords())
+  r := base.NewTester("report-noflag")
+  if err := base.RunTest(r); err == nil {
+    t.Errorf("report-noflag: expected error")
+  }
+  if records := reportRecordsFor("report-noflag", skip); len(records) != 0 {
+    t.Errorf("report-noflag: unexpected records %+v", records)
+  }
+}
+
+func TestReport(t *testing.T) {
+  disableUpdate(t)
+  withReport(t)
+  skip := len(base.ReportRecords())
+  dir := t.TempDir()
+  if err := ioutil.WriteFile(filepath.Join(dir, "report-ok.golden"), []byte("ok\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := ioutil.WriteFile(filepath.Join(dir, "report-bad.golden"), []byte("good\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  for _, name := range []string{"report-ok", "report-bad"} {
+    r := base.NewTester(name)
+    r.BaseDir = dir
+    r.Test = func(r *base.Tester) error {
+      _, err := io.WriteString(r.OutW, "ok\n")
+      return err
+    }
+    err := base.RunTest(r)
+    if (err == nil) != (name == "report-ok") {
+      t.Errorf("%s: unexpected result %v", name, err)
+    }
+  }
+  r := base.NewTester("report-noact")
+  r.BaseDir = dir
+  if err := base.RunTest(r); err == nil {
+    t.Errorf("report-noact: expected error")
+  }
+  r = base.NewTester("report-name")
+  r.BaseDir = dir
+  r.GoldenBaseName = "report-ok"
+  r.Test = func(r *base.Tester) error {
+    _, err := io.WriteString(r.OutW, "ok\n")
+    return err
+  }
+  if err := base.RunTest(r); err != nil {
+    t.Errorf("report-name: %v", err)
+  }
+
+  ok := reportRecordsFor("report-ok", skip)
+  if len(ok) != 1 || !ok[0].Passed || ok[0].Phase != base.PhaseAssert ||
+      ok[0].GoldenPath != filepath.Join(dir, "report-ok.golden") {
+    t.Errorf("report-ok: unexpected records %+v", ok)
+  }
+  bad := reportRecordsFor("report-bad", skip)
+  if len(bad) != 1 || bad[0].Passed || !strings.Contains(bad[0].Diff, "-good") {
+    t.Errorf("report-bad: unexpected records %+v", bad)
+  }
+  if named := reportRecordsFor("report-name", skip); len(named) != 1 || !named[0].Passed {
+    t.Errorf("report-name: unexpected records %+v", named)
+  }
+  noact := reportRecordsFor("report-noact", skip)
+  if len(noact) != 1 || noact[0].Passed || noact[0].Phase != base.PhaseAct || noact[0].GoldenPath != "" {
+    t.Errorf("report-noact: unexpected records %+v", noact)
+  }
+
+  records := append(ok, bad...)
+  var buf bytes.Buffer
+  if err := base.WriteJSONReport(&buf, records); err != nil {
+    t.Fatalf("WriteJSONReport: %v", err)
+  }
+  var decoded []map[string]interface{}
+  if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+    t.Fatalf("error decoding JSON report: %v", err)
+  }
+  if len(decoded) != 2 || decoded[0]["test"] != "report-ok" || decoded[1]["passed"] != false {
+    t.Errorf("unexpected JSON report: %s", buf.String())
+  }
+
+  buf.Reset()
+  if err := base.WriteJUnitReport(&buf, records); err != nil {
+    t.Fatalf("WriteJUnitReport: %v", err)
+  }
+  xml := buf.String()
+  if !strings.Contains(xml, `<testsuite name="golden" tests="2" failures="1"`) ||
+      !strings.Contains(xml, `<failure message="failed in Assert">`) {
+    t.Errorf("unexpected JUnit report: %s", xml)
+  }
+}
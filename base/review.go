@@ -0,0 +1,120 @@
+package base
+
+import (
+  "bytes"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+)
+
+// OutGoldenExtensions maps the extension of each kind of output file
+// written by the testers to the extension of its golden file.
+var OutGoldenExtensions = map[string]string{
+  ".out": ".golden",
+  ".dbout": ".dbgolden",
+  ".sqlout": ".sqlgolden",
+}
+
+// Mismatch is an output file whose content differs from its golden file.
+type Mismatch struct {
+  OutPath string
+  GoldenPath string
+  // Missing is true if the golden file does not exist.
+  Missing bool
+}
+
+// GoldenPathFor returns the path of the golden file that corresponds to
+// the given output file, or the empty string if outpath does not have
+// one of the extensions in OutGoldenExtensions.
+func GoldenPathFor(outpath string) string {
+  ext := filepath.Ext(outpath)
+  goldenExt, ok := OutGoldenExtensions[ext]
+  if !ok {
+    return ""
+  }
+  return strings.TrimSuffix(outpath, ext) + goldenExt
+}
+
+// FindMismatches returns the output files in the given directories and
+// their subdirectories whose content differs from their golden files,
+// sorted by output file path.
+// Output files that match their golden files are not included.
+func FindMismatches(dirs ...string) ([]*Mismatch, error) {
+  mismatches := make([]*Mismatch, 0)
+  for _, dir := range dirs {
+    err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+      if err != nil {
+        return err
+      }
+      if info.IsDir() {
+        return nil
+      }
+      goldenpath := GoldenPathFor(path)
+      if goldenpath == "" {
+        return nil
+      }
+      outcontent, err := ioutil.ReadFile(path)
+      if err != nil {
+        return err
+      }
+      goldencontent, err := ioutil.ReadFile(goldenpath)
+      if os.IsNotExist(err) {
+        mismatches = append(mismatches, &Mismatch{OutPath: path, GoldenPath: goldenpath, Missing: true})
+        return nil
+      }
+      if err != nil {
+        return err
+      }
+      if !bytes.Equal(outcontent, goldencontent) {
+        mismatches = append(mismatches, &Mismatch{OutPath: path, GoldenPath: goldenpath})
+      }
+      return nil
+    })
+    if err != nil {
+      return nil, err
+    }
+  }
+  sort.Slice(mismatches, func(i, j int) bool {
+    return mismatches[i].OutPath < mismatches[j].OutPath
+  })
+  return mismatches, nil
+}
+
+// Diff returns a TextDiff of the golden file to the output file.
+func (m *Mismatch) Diff() (string, error) {
+  outcontent, err := ioutil.ReadFile(m.OutPath)
+  if err != nil {
+    return "", err
+  }
+  goldencontent := []byte{}
+  if !m.Missing {
+    goldencontent, err = ioutil.ReadFile(m.GoldenPath)
+    if err != nil {
+      return "", err
+    }
+  }
+  if !isText(outcontent) || !isText(goldencontent) {
+    return HexReporter{}.Report(outcontent, goldencontent), nil
+  }
+  return TextDiff(string(goldencontent), string(outcontent)), nil
+}
+
+// Accept copies the output file to the golden file.
+func (m *Mismatch) Accept() error {
+  outcontent, err := ioutil.ReadFile(m.OutPath)
+  if err != nil {
+    return err
+  }
+  if err := ioutil.WriteFile(m.GoldenPath, outcontent, 0644); err != nil {
+    return fmt.Errorf("error updating golden file %s: %v", m.GoldenPath, err)
+  }
+  return nil
+}
+
+// Reject removes the output file, leaving the golden file unchanged.
+func (m *Mismatch) Reject() error {
+  return os.Remove(m.OutPath)
+}
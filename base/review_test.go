@@ -0,0 +1,75 @@
+package base_test
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestFindMismatches(t *testing.T) {
+  dir := t.TempDir()
+  files := map[string]string{
+    "same.out": "a\n",
+    "same.golden": "a\n",
+    "changed.out": "a\nc\n",
+    "changed.golden": "a\nb\n",
+    "sub/new.dbout": "x\n",
+    "other.txt": "y\n",
+  }
+  for name, content := range files {
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+      t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  mismatches, err := base.FindMismatches(dir)
+  if err != nil {
+    t.Fatalf("FindMismatches: %v", err)
+  }
+  if got, want := len(mismatches), 2; got != want {
+    t.Fatalf("FindMismatches: got %d mismatches, want %d", got, want)
+  }
+  changed, added := mismatches[0], mismatches[1]
+  if got, want := changed.GoldenPath, filepath.Join(dir, "changed.golden"); got != want {
+    t.Errorf("GoldenPath: got %s, want %s", got, want)
+  }
+  if changed.Missing {
+    t.Errorf("changed.out: Missing should be false")
+  }
+  if got, want := added.GoldenPath, filepath.Join(dir, "sub/new.dbgolden"); got != want {
+    t.Errorf("GoldenPath: got %s, want %s", got, want)
+  }
+  if !added.Missing {
+    t.Errorf("new.dbout: Missing should be true")
+  }
+
+  diff, err := changed.Diff()
+  if err != nil {
+    t.Fatalf("Diff: %v", err)
+  }
+  if !strings.Contains(diff, "-b\n") || !strings.Contains(diff, "+c\n") {
+    t.Errorf("Diff: got %q", diff)
+  }
+
+  if err := changed.Accept(); err != nil {
+    t.Fatalf("Accept: %v", err)
+  }
+  if err := added.Reject(); err != nil {
+    t.Fatalf("Reject: %v", err)
+  }
+  mismatches, err = base.FindMismatches(dir)
+  if err != nil {
+    t.Fatalf("FindMismatches: %v", err)
+  }
+  if len(mismatches) != 0 {
+    t.Errorf("FindMismatches after accept and reject: got %d mismatches, want 0", len(mismatches))
+  }
+}
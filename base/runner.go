@@ -1,8 +1,10 @@
 package base
 
 import (
+  "context"
   "fmt"
   "testing"
+  "time"
 )
 
 // Runner defines the methods used when running one of our unit tests.
@@ -31,45 +33,202 @@ type MultiRunner interface {
   Close() error
 }
 
-// RunTest runs the test on the Runner by executing the Arrange, Act, and Assert functions.
-func RunTest(r Runner) error {
-  // Set things up for our one test.
-  if err := r.Arrange(); err != nil {
-    return fmt.Errorf("error in test Arrange: %v", err)
+// Phase names a step in running a test.
+type Phase string
+
+const (
+  PhaseInit Phase = "Init"
+  PhaseArrange Phase = "Arrange"
+  PhaseAct Phase = "Act"
+  PhaseAssert Phase = "Assert"
+  PhaseClose Phase = "Close"
+)
+
+// PhaseError is the error returned by RunTest and RunOne when a test
+// phase fails. It names the phase and wraps the error from that phase.
+type PhaseError struct {
+  Phase Phase
+  Err error
+}
+
+// Error returns the message of the wrapped error, prefixed by the phase.
+func (e *PhaseError) Error() string {
+  return fmt.Sprintf("error in test %s: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *PhaseError) Unwrap() error {
+  return e.Err
+}
+
+// Result is the outcome of running a test with RunTestResult or RunOneResult.
+type Result struct {
+  // The last phase reached, which is the failing phase if Err is set.
+  Phase Phase
+  // nil if the test passed, else a *PhaseError.
+  Err error
+  // How long each phase that was run took.
+  Durations map[Phase]time.Duration
+}
+
+// Duration returns the total time taken by all of the phases.
+func (res *Result) Duration() time.Duration {
+  var total time.Duration
+  for _, d := range res.Durations {
+    total += d
   }
+  return total
+}
+
+// testRun holds the state of one call to RunTest or RunOne.
+type testRun struct {
+  ctx context.Context
+  r Runner
+  observers []Observer
+  res *Result
+}
 
-  // Perform the test action.
-  if err := r.Act(); err != nil {
-    return fmt.Errorf("error in test Act: %v", err)
+// newTestRun sets the context on the runner, if it has a SetContext method,
+// and returns a testRun for it.
+func newTestRun(ctx context.Context, r Runner) *testRun {
+  if c, ok := r.(interface{ SetContext(context.Context) }); ok {
+    c.SetContext(ctx)
   }
+  return &testRun{
+    ctx: ctx,
+    r: r,
+    observers: observersFor(r),
+    res: &Result{Durations: make(map[Phase]time.Duration)},
+  }
+}
 
-  // Check the output against the golden file.
-  if err := r.Assert(); err != nil {
-    return fmt.Errorf("error in test Assert: %v", err)
+// runPhase runs one phase of a test, calling the observers before and
+// after it, and recording its duration in the Result.
+// It returns false if the phase failed.
+func (t *testRun) runPhase(phase Phase, f func() error) bool {
+  t.res.Phase = phase
+  for _, o := range t.observers {
+    o.BeforePhase(t.r, phase)
+  }
+  start := time.Now()
+  err := t.callPhase(phase, f)
+  t.res.Durations[phase] = time.Since(start)
+  for _, o := range t.observers {
+    if oerr := o.AfterPhase(t.r, phase, err); err == nil {
+      err = oerr
+    }
   }
+  if err != nil {
+    t.res.Err = &PhaseError{Phase: phase, Err: err}
+    return false
+  }
+  return true
+}
 
-  return nil
+// callPhase calls f. For the Act phase, if the context can be canceled,
+// it runs f on a goroutine and returns an error as soon as the context is
+// done, even if f has not returned. In that case f is left running until it
+// returns on its own, so code under test should stop when its context is
+// done. An error from f after the context is done is also reported as a
+// context error, since f may have failed because of the cancellation.
+// The other phases set up and check the test, so they are always run to
+// completion.
+func (t *testRun) callPhase(phase Phase, f func() error) error {
+  if phase != PhaseAct || t.ctx.Done() == nil {
+    return f()
+  }
+  if err := t.ctx.Err(); err != nil {
+    return t.contextError(phase)
+  }
+  done := make(chan error, 1)
+  go func() {
+    done <- f()
+  }()
+  select {
+  case err := <-done:
+    if err != nil && t.ctx.Err() != nil {
+      return t.contextError(phase)
+    }
+    return err
+  case <-t.ctx.Done():
+    return t.contextError(phase)
+  }
+}
+
+// contextError returns the error for a test whose context ended during the phase.
+func (t *testRun) contextError(phase Phase) error {
+  name := fmt.Sprintf("%T", t.r)
+  if n, ok := t.r.(interface{ TestName() string }); ok {
+    name = n.TestName()
+  }
+  return fmt.Errorf("test %s did not finish %s: %w", name, phase, t.ctx.Err())
+}
+
+// runTest runs Arrange, Act and Assert.
+func (t *testRun) runTest() bool {
+  // Set things up for our one test, perform the test action,
+  // and check the output against the golden file.
+  return t.runPhase(PhaseArrange, t.r.Arrange) &&
+      t.runPhase(PhaseAct, t.r.Act) &&
+      t.runPhase(PhaseAssert, t.r.Assert)
+}
+
+// RunTest runs the test on the Runner by executing the Arrange, Act, and Assert functions.
+// If one of those fails, it returns a *PhaseError.
+// The result is added to the report written by Main when -golden.report is set.
+func RunTest(r Runner) error {
+  return RunTestResult(r).Err
+}
+
+// RunTestContext is like RunTest but passes ctx to the runner, if it has
+// a SetContext method, and fails the test if ctx is done before Act
+// finishes. A timed-out Act is not stopped: it keeps running in the
+// background until it returns, so it should watch ctx.
+func RunTestContext(ctx context.Context, r Runner) error {
+  return runTestResult(ctx, r).Err
+}
+
+// RunTestResult is like RunTest but returns a Result that also records
+// how long each phase took.
+func RunTestResult(r Runner) *Result {
+  return runTestResult(context.Background(), r)
+}
+
+func runTestResult(ctx context.Context, r Runner) *Result {
+  t := newTestRun(ctx, r)
+  t.runTest()
+  addReportRecords(r, t.res)
+  return t.res
 }
 
 // RunOne runs one test on the MultiRunner by executing
 // Init, then running RunTest, then Close.
+// If one of those fails, it returns a *PhaseError.
 func RunOne(r MultiRunner) error {
-  // Do the one-time initialization.
-  if err := r.Init(); err != nil {
-    return fmt.Errorf("error in test Init: %v", err)
-  }
+  return RunOneResult(r).Err
+}
 
-  // Run one test.
-  if err := RunTest(r); err != nil {
-    return err
-  }
+// RunOneContext is like RunOne but passes ctx to the runner, if it has
+// a SetContext method, and fails the test if ctx is done before Act
+// finishes, as RunTestContext does.
+func RunOneContext(ctx context.Context, r MultiRunner) error {
+  return runOneResult(ctx, r).Err
+}
 
-  // Clean up.
-  if err := r.Close(); err != nil {
-    return fmt.Errorf("error in test Close: %v", err)
-  }
+// RunOneResult is like RunOne but returns a Result that also records
+// how long each phase took.
+func RunOneResult(r MultiRunner) *Result {
+  return runOneResult(context.Background(), r)
+}
 
-  return nil
+func runOneResult(ctx context.Context, r MultiRunner) *Result {
+  t := newTestRun(ctx, r)
+  // Do the one-time initialization, run one test, and clean up.
+  if t.runPhase(PhaseInit, r.Init) && t.runTest() {
+    t.runPhase(PhaseClose, r.Close)
+  }
+  addReportRecords(r, t.res)
+  return t.res
 }
 
 // FatalIfError calls testing.T.Fatal if there is an error.
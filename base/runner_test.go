@@ -1,8 +1,11 @@
 package base_test
 
 import (
+  "context"
+  "errors"
   "io"
   "testing"
+  "time"
 
   "github.com/jimmc/golden/base"
 )
@@ -18,3 +21,90 @@ func TestRunOne(t *testing.T) {
     t.Fatalf("Error in Run: %v", err)
   }
 }
+
+func TestRunOneResult(t *testing.T) {
+  r := base.NewTester("run-example")
+  r.Test = func(r *base.Tester) error {
+    _, err := io.WriteString(r.OutW, example("run"))
+    return err
+  }
+  res := base.RunOneResult(r)
+  if res.Err != nil {
+    t.Fatalf("Error in Run: %v", res.Err)
+  }
+  if res.Phase != base.PhaseClose {
+    t.Errorf("Phase: got %s, want %s", res.Phase, base.PhaseClose)
+  }
+  for _, phase := range []base.Phase{base.PhaseInit, base.PhaseArrange, base.PhaseAct, base.PhaseAssert, base.PhaseClose} {
+    if _, ok := res.Durations[phase]; !ok {
+      t.Errorf("no duration for phase %s", phase)
+    }
+  }
+}
+
+func TestRunTestPhaseError(t *testing.T) {
+  actErr := errors.New("act failed")
+  r := base.NewTester("run-example")
+  r.Test = func(r *base.Tester) error {
+    return actErr
+  }
+  res := base.RunTestResult(r)
+  var phaseErr *base.PhaseError
+  if !errors.As(res.Err, &phaseErr) {
+    t.Fatalf("expected PhaseError, got %v", res.Err)
+  }
+  if phaseErr.Phase != base.PhaseAct || res.Phase != base.PhaseAct {
+    t.Errorf("Phase: got %s, want %s", phaseErr.Phase, base.PhaseAct)
+  }
+  if !errors.Is(res.Err, actErr) {
+    t.Errorf("error does not wrap the Act error: %v", res.Err)
+  }
+  if got, want := res.Err.Error(), "error in test Act: act failed"; got != want {
+    t.Errorf("Error: got %q, want %q", got, want)
+  }
+  if _, ok := res.Durations[base.PhaseAssert]; ok {
+    t.Errorf("Assert should not have run")
+  }
+}
+
+func TestRunTestContext(t *testing.T) {
+  ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+  defer cancel()
+  release := make(chan bool)
+  defer close(release)
+  r := base.NewTester("run-timeout")
+  r.Test = func(r *base.Tester) error {
+    if r.Context() != ctx {
+      return errors.New("test does not have the context")
+    }
+    <-release
+    return nil
+  }
+  err := base.RunTestContext(ctx, r)
+  if !errors.Is(err, context.DeadlineExceeded) {
+    t.Fatalf("expected deadline exceeded, got %v", err)
+  }
+  if got, want := err.Error(), "error in test Act: test run-timeout did not finish Act: context deadline exceeded"; got != want {
+    t.Errorf("error: got %q, want %q", got, want)
+  }
+}
+
+// cancelRunner is a Runner whose Assert cancels the context of the test.
+type cancelRunner struct {
+  cancel context.CancelFunc
+}
+
+func (r *cancelRunner) Arrange() error { return nil }
+func (r *cancelRunner) Act() error { return nil }
+func (r *cancelRunner) Assert() error {
+  r.cancel()
+  return nil
+}
+
+func TestRunTestContextOnlyTimesAct(t *testing.T) {
+  ctx, cancel := context.WithCancel(context.Background())
+  defer cancel()
+  if err := base.RunTestContext(ctx, &cancelRunner{cancel: cancel}); err != nil {
+    t.Errorf("Assert should run to completion, got %v", err)
+  }
+}
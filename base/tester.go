@@ -2,10 +2,14 @@ package base
 
 import (
   "bufio"
+  "context"
   "errors"
   "fmt"
+  "io/ioutil"
   "os"
   "path"
+  "strings"
+  "time"
 )
 
 // Tester allows for configuring and running the different steps of the test.
@@ -25,13 +29,71 @@ type Tester struct {
   // Path to the golden file; if not set, uses GoldenBaseName.
   GoldenPath string
 
+  // Comparator for the output file, or for the files in the output
+  // directory when DirOutput is set; if not set, uses DefaultComparator.
+  Comparator Comparator
+  // Comparators for the named outputs created by Output, keyed by name;
+  // a named output with no comparator here uses DefaultComparator.
+  OutputComparators map[string]Comparator
+
+  // If true, the test writes its output as files in OutDir, and Assert
+  // compares that directory tree to the golden directory (see GoldenDirPath)
+  // instead of comparing the output file to the golden file.
+  DirOutput bool
+  // If true, comparing directory trees also reports files whose
+  // executable bit differs.
+  CompareModes bool
+
   // Function to run the test.
   Test func(*Tester) error
 
+  // Observers called before and after each phase of this test,
+  // after any added by AddObserver.
+  Observers []Observer
+
   // The output file.
   OutF *os.File;
   // A Writer that can be used to write to the output file.
   OutW *bufio.Writer;
+  // The output directory, when DirOutput is set.
+  OutDir string
+
+  // Additional named outputs created by Output, in the order created.
+  outputs []*namedOutput
+  // The comparisons made by Assert in the current test.
+  comparisons []Comparison
+  // The context set by RunTestContext or RunOneContext.
+  ctx context.Context
+}
+
+// Comparison records one comparison of an output file or directory
+// to its golden file or directory.
+type Comparison struct {
+  OutPath string
+  GoldenPath string
+  // The result of the comparison; nil if the output matched.
+  Err error
+  Duration time.Duration
+}
+
+// namedOutput is an additional output file created by Tester.Output.
+type namedOutput struct {
+  name string
+  f *os.File
+  w *bufio.Writer
+  err error
+}
+
+// MultiError is a list of errors reported together.
+type MultiError []error
+
+// Error returns the messages of all of the errors, one per line.
+func (e MultiError) Error() string {
+  messages := make([]string, len(e))
+  for i, err := range e {
+    messages[i] = err.Error()
+  }
+  return strings.Join(messages, "\n")
 }
 
 // NewTester creates a new Tester instance.
@@ -52,6 +114,67 @@ func (r *Tester) GoldenFilePath() string {
   return r.GetFilePath(r.GoldenPath, r.GoldenBaseName, "golden")
 }
 
+// OutDirPath returns the complete path to the output directory
+// used when DirOutput is set.
+func (r *Tester) OutDirPath() string {
+  return r.GetFilePath("", r.OutBaseName, "outdir")
+}
+
+// GoldenDirPath returns the complete path to the golden directory
+// used when DirOutput is set. This is the same as the golden file path.
+func (r *Tester) GoldenDirPath() string {
+  return r.GoldenFilePath()
+}
+
+// NamedOutFilePath returns the complete path to the output file
+// for the named output, which is <OutBaseName>.<name>.out.
+func (r *Tester) NamedOutFilePath(name string) string {
+  return r.GetFilePath("", r.OutBaseName, name + ".out")
+}
+
+// NamedGoldenFilePath returns the complete path to the golden file
+// for the named output, which is <GoldenBaseName>.<name>.golden.
+func (r *Tester) NamedGoldenFilePath(name string) string {
+  return r.GetFilePath("", r.GoldenBaseName, name + ".golden")
+}
+
+// Output returns a Writer for the named output, creating the output file
+// the first time it is called for a name after Arrange. Assert compares
+// each named output file to its golden file as well as the main output file.
+// If the output file can not be created, the returned Writer discards its
+// output and Assert reports the error.
+func (r *Tester) Output(name string) *bufio.Writer {
+  for _, o := range r.outputs {
+    if o.name == name {
+      return o.w
+    }
+  }
+  o := &namedOutput{name: name}
+  outfilepath := r.NamedOutFilePath(name)
+  RecordUsed(outfilepath)
+  os.Remove(outfilepath)
+  f, err := os.Create(outfilepath)
+  if err != nil {
+    o.err = fmt.Errorf("error creating output file %q: %v", outfilepath, err)
+    o.w = bufio.NewWriter(ioutil.Discard)
+  } else {
+    o.f = f
+    o.w = bufio.NewWriter(f)
+  }
+  r.outputs = append(r.outputs, o)
+  return o.w
+}
+
+// closeOutputs flushes and closes all of the named outputs.
+func (r *Tester) closeOutputs() {
+  for _, o := range r.outputs {
+    o.w.Flush()
+    if o.f != nil {
+      o.f.Close()
+    }
+  }
+}
+
 // GetFilePath calculates and returns the complete path to a file.
 // If fpath is set, it returns it, else it uses basename, with default "test",
 // and the Tester's BaseDir, with default "testdata", plus the given extension
@@ -79,9 +202,14 @@ func (r *Tester) Init() error {
 }
 
 // Arrange creates the output files for the test to write to and sets
-// OutF and OutW in the Tester.
+// OutF and OutW in the Tester. It discards any named outputs from the
+// previous test.
 func (r *Tester) Arrange() error {
+  r.closeOutputs()
+  r.outputs = nil
+  r.comparisons = nil
   outfilepath := r.OutFilePath()
+  RecordUsed(outfilepath)
   os.Remove(outfilepath)
   f, err := os.Create(outfilepath)
   if err != nil {
@@ -91,6 +219,17 @@ func (r *Tester) Arrange() error {
 
   r.OutF = f
   r.OutW = w
+
+  if r.DirOutput {
+    outdirpath := r.OutDirPath()
+    if err := os.RemoveAll(outdirpath); err != nil {
+      return fmt.Errorf("error removing output directory %q: %v", outdirpath, err)
+    }
+    if err := os.MkdirAll(outdirpath, 0755); err != nil {
+      return fmt.Errorf("error creating output directory %q: %v", outdirpath, err)
+    }
+    r.OutDir = outdirpath
+  }
   return nil
 }
 
@@ -102,11 +241,89 @@ func (r *Tester) Act() error {
   return r.Test(r)
 }
 
-// Assert closes the output and compares it to the golden file.
+// Assert closes the output and compares it to the golden file, or compares
+// the output directory to the golden directory if DirOutput is set, then
+// compares each named output to its golden file. If there are any mismatches,
+// it returns a MultiError that reports all of them.
 func (r *Tester) Assert() error {
   r.OutW.Flush()
   r.OutF.Close()
-  return CompareOutToGolden(r.OutFilePath(), r.GoldenFilePath())
+  r.closeOutputs()
+  errs := make(MultiError, 0)
+  if r.DirOutput {
+    if err := r.CompareDir(r.OutDirPath(), r.GoldenDirPath()); err != nil {
+      errs = append(errs, err)
+    }
+  } else if err := r.CompareFile(r.OutFilePath(), r.GoldenFilePath(), r.Comparator); err != nil {
+    errs = append(errs, err)
+  }
+  for _, o := range r.outputs {
+    if o.err != nil {
+      errs = append(errs, o.err)
+    } else if err := r.CompareFile(r.NamedOutFilePath(o.name), r.NamedGoldenFilePath(o.name), r.OutputComparators[o.name]); err != nil {
+      errs = append(errs, err)
+    }
+  }
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
+  }
+  return errs
+}
+
+// CompareFile compares an output file to its golden file using
+// CompareOutToGoldenWith and records the comparison for Comparisons.
+func (r *Tester) CompareFile(outfilepath, goldenfilepath string, comparator Comparator) error {
+  start := time.Now()
+  err := CompareOutToGoldenWith(outfilepath, goldenfilepath, comparator)
+  r.comparisons = append(r.comparisons, Comparison{outfilepath, goldenfilepath, err, time.Since(start)})
+  return err
+}
+
+// CompareDir compares an output directory to its golden directory using
+// CompareDirToGoldenWith with Comparator and records the comparison for Comparisons.
+func (r *Tester) CompareDir(outdirpath, goldendirpath string) error {
+  start := time.Now()
+  err := CompareDirToGoldenWith(outdirpath, goldendirpath, r.CompareModes, r.Comparator)
+  r.comparisons = append(r.comparisons, Comparison{outdirpath, goldendirpath, err, time.Since(start)})
+  return err
+}
+
+// Comparisons returns the comparisons made by Assert since the last Arrange.
+func (r *Tester) Comparisons() []Comparison {
+  return r.comparisons
+}
+
+// SetContext sets the context returned by Context. RunTest and RunOne call
+// this before running the test.
+func (r *Tester) SetContext(ctx context.Context) {
+  r.ctx = ctx
+}
+
+// Context returns the context for the current test, as passed to
+// RunTestContext or RunOneContext, or context.Background if none was set.
+// The test function should use this for any operations that can block.
+func (r *Tester) Context() context.Context {
+  if r.ctx == nil {
+    return context.Background()
+  }
+  return r.ctx
+}
+
+// PhaseObservers returns the Observers for this Tester.
+func (r *Tester) PhaseObservers() []Observer {
+  return r.Observers
+}
+
+// TestName returns the name of the test for use in reports and errors,
+// which is the base name.
+func (r *Tester) TestName() string {
+  if r.BaseName != "" {
+    return r.BaseName
+  }
+  return "test"
 }
 
 // Close is a no-op in this Tester.
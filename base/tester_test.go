@@ -4,6 +4,7 @@ import (
   "errors"
   "fmt"
   "io"
+  "strings"
   "testing"
 
   "github.com/jimmc/golden/base"
@@ -81,6 +82,7 @@ func TestActError(t *testing.T) {
 }
 
 func TestNoGoldenFile(t *testing.T) {
+  disableUpdate(t)
   r := base.NewTester("example-no-golden")
   r.Test = func(r *base.Tester) error {
     s := example("no-golden")
@@ -166,3 +168,70 @@ func TestTwoTests(t *testing.T) {
     t.Fatalf("Error in Close: %v", err)
   }
 }
+
+func TestNamedOutputs(t *testing.T) {
+  r := base.NewTester("multi")
+  r.Test = func(r *base.Tester) error {
+    io.WriteString(r.OutW, example("main"))
+    io.WriteString(r.Output("log"), example("log"))
+    io.WriteString(r.Output("report"), example("report"))
+    _, err := io.WriteString(r.Output("log"), example("log again"))
+    return err
+  }
+  if got, want := r.NamedOutFilePath("log"), "testdata/multi.log.out"; got != want {
+    t.Errorf("NamedOutFilePath: got %q, want %q", got, want)
+  }
+  if got, want := r.NamedGoldenFilePath("log"), "testdata/multi.log.golden"; got != want {
+    t.Errorf("NamedGoldenFilePath: got %q, want %q", got, want)
+  }
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestNamedOutputComparators(t *testing.T) {
+  disableUpdate(t)
+  r := base.NewTester("multi")
+  r.Comparator = matchAll{}
+  r.OutputComparators = map[string]base.Comparator{"report": matchAll{}}
+  r.Test = func(r *base.Tester) error {
+    io.WriteString(r.OutW, example("wrong main"))
+    io.WriteString(r.Output("log"), example("wrong log"))
+    _, err := io.WriteString(r.Output("report"), example("wrong report"))
+    return err
+  }
+  err := base.RunOne(r)
+  if err == nil {
+    t.Fatalf("Expected error for mismatched log")
+  }
+  // Only the log, which has no comparator, should be reported.
+  var errs base.MultiError
+  if errors.As(err, &errs) || !strings.Contains(err.Error(), "multi.log.golden") {
+    t.Errorf("unexpected error: %v", err)
+  }
+}
+
+func TestNamedOutputsMismatch(t *testing.T) {
+  disableUpdate(t)
+  r := base.NewTester("multi")
+  r.Test = func(r *base.Tester) error {
+    io.WriteString(r.OutW, example("main"))
+    io.WriteString(r.Output("log"), example("wrong log"))
+    _, err := io.WriteString(r.Output("report"), example("wrong report"))
+    return err
+  }
+  if err := r.Arrange(); err != nil {
+    t.Fatalf("Error in Arrange: %v", err)
+  }
+  if err := r.Act(); err != nil {
+    t.Fatalf("Error in Act: %v", err)
+  }
+  err := r.Assert()
+  errs, ok := err.(base.MultiError)
+  if !ok {
+    t.Fatalf("Expected MultiError from Assert, got %v", err)
+  }
+  if got, want := len(errs), 2; got != want {
+    t.Errorf("Number of mismatches: got %d, want %d", got, want)
+  }
+}
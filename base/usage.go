@@ -0,0 +1,180 @@
+package base
+
+import (
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "sync"
+  "testing"
+)
+
+var (
+  // ReportStale is set by the -golden.stale flag. When true, Main reports
+  // testdata files that were not used by any test.
+  ReportStale = flag.Bool("golden.stale", false, "report testdata files not used by any test")
+  // DeleteStale is set by the -golden.delete-stale flag. When true, Main
+  // deletes testdata files that were not used by any test.
+  DeleteStale = flag.Bool("golden.delete-stale", false, "delete testdata files not used by any test")
+)
+
+// StaleExtensions lists the extensions of the files that FindStale checks.
+// Files with other extensions are never considered stale.
+// By default it lists only the files that the testers own; callers whose
+// testdata holds nothing else of that kind can append others, such as
+// ".sql" query files or ".json" fixture files.
+var StaleExtensions = []string{
+  ".golden", ".out", ".setup",
+  ".dbgolden", ".dbout", ".sqlgolden", ".sqlout",
+  ".response", ".cassette",
+}
+
+var (
+  usageMu sync.Mutex
+  // The absolute paths of the files and directories used by tests.
+  usedPaths = make(map[string]bool)
+)
+
+// RecordUsed records that a test used the file or directory at path.
+// The testers call this for every setup, output and golden file they use,
+// so that FindStale can find the files that no test used.
+// Using a directory counts as using everything in it.
+func RecordUsed(path string) {
+  abs, err := filepath.Abs(path)
+  if err != nil {
+    return
+  }
+  usageMu.Lock()
+  defer usageMu.Unlock()
+  usedPaths[abs] = true
+}
+
+// isUsed returns true if the path was recorded as used.
+func isUsed(abs string) bool {
+  usageMu.Lock()
+  defer usageMu.Unlock()
+  return usedPaths[abs]
+}
+
+// hasStaleExtension returns true if the name ends with one of StaleExtensions.
+func hasStaleExtension(name string) bool {
+  for _, ext := range StaleExtensions {
+    if strings.HasSuffix(name, ext) {
+      return true
+    }
+  }
+  return false
+}
+
+// FindStale returns the paths of the files in the given directories and
+// their subdirectories that have one of StaleExtensions and that were not
+// recorded as used by RecordUsed. A directory with one of StaleExtensions,
+// such as a golden directory, is reported as a whole if it was not used.
+// Directories that do not exist are ignored.
+func FindStale(dirs ...string) ([]string, error) {
+  stale := make([]string, 0)
+  for _, dir := range dirs {
+    if _, err := os.Stat(dir); os.IsNotExist(err) {
+      continue
+    }
+    err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+      if err != nil {
+        return err
+      }
+      abs, err := filepath.Abs(path)
+      if err != nil {
+        return err
+      }
+      if isUsed(abs) {
+        if info.IsDir() {
+          return filepath.SkipDir
+        }
+        return nil
+      }
+      if path == dir || !hasStaleExtension(info.Name()) {
+        return nil
+      }
+      stale = append(stale, path)
+      if info.IsDir() {
+        return filepath.SkipDir
+      }
+      return nil
+    })
+    if err != nil {
+      return nil, err
+    }
+  }
+  sort.Strings(stale)
+  return stale, nil
+}
+
+// CheckStale finds the stale files in dirs with FindStale and writes a
+// line to w for each one. If deleteStale is true, it deletes them.
+// It returns an error if there are stale files that it did not delete.
+func CheckStale(w io.Writer, deleteStale bool, dirs ...string) error {
+  stale, err := FindStale(dirs...)
+  if err != nil {
+    return fmt.Errorf("error checking for stale files: %v", err)
+  }
+  remaining := 0
+  for _, path := range stale {
+    if deleteStale {
+      if err := os.RemoveAll(path); err != nil {
+        fmt.Fprintf(w, "golden: error deleting stale file %s: %v\n", path, err)
+        remaining++
+        continue
+      }
+      fmt.Fprintf(w, "golden: deleted stale file %s\n", path)
+    } else {
+      fmt.Fprintf(w, "golden: stale file %s\n", path)
+      remaining++
+    }
+  }
+  if remaining > 0 {
+    return fmt.Errorf("%d stale files", remaining)
+  }
+  return nil
+}
+
+// Main runs the tests by calling m.Run, and returns the exit code to pass
+// to os.Exit. If the -golden.report flag is set, it then writes the report
+// of golden results. After a successful run of all tests, if the
+// -golden.stale or -golden.delete-stale flag is set, it reports or deletes
+// the stale files in dirs, or in "testdata" if no dirs are given, with
+// CheckStale. Its messages go to stderr. The exit code is non-zero if
+// there are stale files left.
+// Typical use is:
+//   func TestMain(m *testing.M) {
+//     os.Exit(base.Main(m))
+//   }
+func Main(m *testing.M, dirs ...string) int {
+  code := m.Run()
+  if *ReportPath != "" {
+    if err := WriteReportFile(*ReportPath); err != nil {
+      fmt.Fprintf(os.Stderr, "golden: %v\n", err)
+      code = 1
+    }
+  }
+  if !*ReportStale && !*DeleteStale {
+    return code
+  }
+  if code != 0 {
+    fmt.Fprintln(os.Stderr, "golden: not checking for stale files because tests failed")
+    return code
+  }
+  if f := flag.Lookup("test.run"); f != nil && f.Value.String() != "" {
+    fmt.Fprintln(os.Stderr, "golden: not checking for stale files because -test.run is set")
+    return code
+  }
+  if len(dirs) == 0 {
+    dirs = []string{"testdata"}
+  }
+  if err := CheckStale(os.Stderr, *DeleteStale, dirs...); err != nil {
+    fmt.Fprintf(os.Stderr, "golden: %v\n", err)
+    return 1
+  }
+  return code
+}
@@ -0,0 +1,82 @@
+package base_test
+
+import (
+  "bytes"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+func TestFindStale(t *testing.T) {
+  dir := t.TempDir()
+  for _, name := range []string{"a.golden", "b.golden", "c.txt", "c.json", "x.golden/f", "sub/d.setup"} {
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+      t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(path, []byte("x\n"), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+  base.RecordUsed(filepath.Join(dir, "a.golden"))
+  base.RecordUsed(filepath.Join(dir, "x.golden"))
+
+  stale, err := base.FindStale(dir, filepath.Join(dir, "nosuchdir"))
+  if err != nil {
+    t.Fatalf("FindStale: %v", err)
+  }
+  want := []string{filepath.Join(dir, "b.golden"), filepath.Join(dir, "sub/d.setup")}
+  if len(stale) != len(want) {
+    t.Fatalf("FindStale: got %v, want %v", stale, want)
+  }
+  for i := range want {
+    if stale[i] != want[i] {
+      t.Errorf("FindStale[%d]: got %s, want %s", i, stale[i], want[i])
+    }
+  }
+}
+
+func TestNamedOutputIsUsed(t *testing.T) {
+  dir := t.TempDir()
+  r := base.NewTester("named")
+  r.BaseDir = dir
+  r.Output("log")
+  r.Close()
+  stale, err := base.FindStale(dir)
+  if err != nil {
+    t.Fatalf("FindStale: %v", err)
+  }
+  if len(stale) != 0 {
+    t.Errorf("FindStale after Output: got %v, want none", stale)
+  }
+}
+
+func TestCheckStale(t *testing.T) {
+  dir := t.TempDir()
+  path := filepath.Join(dir, "old.golden")
+  if err := ioutil.WriteFile(path, []byte("x\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  var buf bytes.Buffer
+  if err := base.CheckStale(&buf, false, dir); err == nil {
+    t.Errorf("expected error for stale file")
+  }
+  if got, want := buf.String(), "golden: stale file " + path + "\n"; got != want {
+    t.Errorf("report: got %q, want %q", got, want)
+  }
+
+  buf.Reset()
+  if err := base.CheckStale(&buf, true, dir); err != nil {
+    t.Errorf("error deleting stale file: %v", err)
+  }
+  if got, want := buf.String(), "golden: deleted stale file " + path + "\n"; got != want {
+    t.Errorf("report: got %q, want %q", got, want)
+  }
+  if _, err := os.Stat(path); !os.IsNotExist(err) {
+    t.Errorf("stale file was not deleted")
+  }
+}
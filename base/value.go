@@ -0,0 +1,280 @@
+package base
+
+import (
+  "fmt"
+  "io"
+  "reflect"
+  "sort"
+  "strconv"
+  "strings"
+  "testing"
+  "time"
+)
+
+// SerializeOptions controls how SerializeValue formats a value.
+type SerializeOptions struct {
+  // If true, unexported struct fields are included.
+  Unexported bool
+}
+
+// AssertGoldenValue serializes v with SerializeValue and compares the result
+// to the golden file for name, using the same file naming conventions as
+// NewTester(name). If the value does not match the golden file, it reports
+// the error, which includes the line differences, with t.Errorf.
+func AssertGoldenValue(t *testing.T, name string, v interface{}) {
+  t.Helper()
+  AssertGoldenValueWith(t, name, v, SerializeOptions{})
+}
+
+// AssertGoldenValueWith is like AssertGoldenValue with the given options.
+func AssertGoldenValueWith(t *testing.T, name string, v interface{}, opts SerializeOptions) {
+  t.Helper()
+  s := SerializeValue(v, opts)
+  r := NewTester(name)
+  r.Test = func(r *Tester) error {
+    _, err := io.WriteString(r.OutW, s)
+    return err
+  }
+  if err := RunTest(r); err != nil {
+    t.Errorf("AssertGoldenValue %s: %v", name, err)
+  }
+}
+
+// SerializeValue returns a stable, diff-friendly text form of v.
+// Structs are written with one field per line, maps are written with
+// their keys sorted, and pointers are followed, with a pointer, map or
+// slice back to a value that is already being written shown as <cycle>.
+// Functions, channels, and unsafe pointers are written by type only,
+// since their values are not stable.
+func SerializeValue(v interface{}, opts SerializeOptions) string {
+  s := &serializer{
+    opts: opts,
+    visiting: make(map[visit]bool),
+  }
+  s.write(reflect.ValueOf(v), 0)
+  s.sb.WriteString("\n")
+  return s.sb.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// visit identifies a pointer, map or slice by its address and type.
+type visit struct {
+  ptr uintptr
+  typ reflect.Type
+}
+
+type serializer struct {
+  opts SerializeOptions
+  sb strings.Builder
+  // The pointers, maps and slices currently being written.
+  visiting map[visit]bool
+}
+
+// enter marks v as being written and returns true, or returns false
+// if it is already being written.
+func (s *serializer) enter(v reflect.Value) bool {
+  key := visit{v.Pointer(), v.Type()}
+  if s.visiting[key] {
+    return false
+  }
+  s.visiting[key] = true
+  return true
+}
+
+// leave marks v as no longer being written.
+func (s *serializer) leave(v reflect.Value) {
+  delete(s.visiting, visit{v.Pointer(), v.Type()})
+}
+
+func (s *serializer) indent(depth int) {
+  s.sb.WriteString(strings.Repeat("  ", depth))
+}
+
+func (s *serializer) write(v reflect.Value, depth int) {
+  if !v.IsValid() {
+    s.sb.WriteString("nil")
+    return
+  }
+  if v.Type() == timeType && v.CanInterface() {
+    t := v.Interface().(time.Time)
+    fmt.Fprintf(&s.sb, "time.Time(%q)", t.Format(time.RFC3339Nano))
+    return
+  }
+  switch v.Kind() {
+  case reflect.Bool:
+    s.sb.WriteString(strconv.FormatBool(v.Bool()))
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    s.sb.WriteString(strconv.FormatInt(v.Int(), 10))
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    s.sb.WriteString(strconv.FormatUint(v.Uint(), 10))
+  case reflect.Float32, reflect.Float64:
+    s.sb.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+  case reflect.Complex64, reflect.Complex128:
+    s.sb.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))
+  case reflect.String:
+    s.sb.WriteString(strconv.Quote(v.String()))
+  case reflect.Ptr:
+    if v.IsNil() {
+      s.sb.WriteString("nil")
+      return
+    }
+    if !s.enter(v) {
+      s.sb.WriteString("<cycle>")
+      return
+    }
+    s.sb.WriteString("&")
+    s.write(v.Elem(), depth)
+    s.leave(v)
+  case reflect.Interface:
+    if v.IsNil() {
+      s.sb.WriteString("nil")
+      return
+    }
+    s.write(v.Elem(), depth)
+  case reflect.Slice:
+    if v.IsNil() {
+      fmt.Fprintf(&s.sb, "%s(nil)", v.Type())
+      return
+    }
+    if v.Type().Elem().Kind() == reflect.Uint8 {
+      fmt.Fprintf(&s.sb, "%s(%q)", v.Type(), v.Bytes())
+      return
+    }
+    if v.Len() > 0 {
+      if !s.enter(v) {
+        s.sb.WriteString("<cycle>")
+        return
+      }
+      defer s.leave(v)
+    }
+    s.writeList(v, depth)
+  case reflect.Array:
+    s.writeList(v, depth)
+  case reflect.Map:
+    s.writeMap(v, depth)
+  case reflect.Struct:
+    s.writeStruct(v, depth)
+  default:
+    // Func, Chan, UnsafePointer.
+    if v.IsNil() {
+      fmt.Fprintf(&s.sb, "%s(nil)", v.Type())
+    } else {
+      fmt.Fprintf(&s.sb, "%s{...}", v.Type())
+    }
+  }
+}
+
+func (s *serializer) writeList(v reflect.Value, depth int) {
+  fmt.Fprintf(&s.sb, "%s{", v.Type())
+  if v.Len() == 0 {
+    s.sb.WriteString("}")
+    return
+  }
+  s.sb.WriteString("\n")
+  for i := 0; i < v.Len(); i++ {
+    s.indent(depth + 1)
+    s.write(v.Index(i), depth + 1)
+    s.sb.WriteString(",\n")
+  }
+  s.indent(depth)
+  s.sb.WriteString("}")
+}
+
+func (s *serializer) writeMap(v reflect.Value, depth int) {
+  if v.IsNil() {
+    fmt.Fprintf(&s.sb, "%s(nil)", v.Type())
+    return
+  }
+  if !s.enter(v) {
+    s.sb.WriteString("<cycle>")
+    return
+  }
+  defer s.leave(v)
+  fmt.Fprintf(&s.sb, "%s{", v.Type())
+  if v.Len() == 0 {
+    s.sb.WriteString("}")
+    return
+  }
+  type entry struct {
+    key string
+    keyValue reflect.Value
+    value reflect.Value
+  }
+  entries := make([]entry, 0, v.Len())
+  iter := v.MapRange()
+  for iter.Next() {
+    ks := &serializer{opts: s.opts, visiting: s.visiting}
+    ks.write(iter.Key(), depth + 1)
+    entries = append(entries, entry{key: ks.sb.String(), keyValue: iter.Key(), value: iter.Value()})
+  }
+  sort.Slice(entries, func(i, j int) bool {
+    if less, ok := lessKey(entries[i].keyValue, entries[j].keyValue); ok {
+      return less
+    }
+    return entries[i].key < entries[j].key
+  })
+  s.sb.WriteString("\n")
+  for _, e := range entries {
+    s.indent(depth + 1)
+    s.sb.WriteString(e.key)
+    s.sb.WriteString(": ")
+    s.write(e.value, depth + 1)
+    s.sb.WriteString(",\n")
+  }
+  s.indent(depth)
+  s.sb.WriteString("}")
+}
+
+// lessKey compares two map keys of the same kind by value, so that
+// numeric keys sort as 1, 2, 10 rather than as text. It returns false
+// for ok if the keys can not be compared that way.
+func lessKey(a, b reflect.Value) (less, ok bool) {
+  for a.Kind() == reflect.Interface && !a.IsNil() {
+    a = a.Elem()
+  }
+  for b.Kind() == reflect.Interface && !b.IsNil() {
+    b = b.Elem()
+  }
+  if a.Kind() != b.Kind() {
+    return false, false
+  }
+  switch a.Kind() {
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    return a.Int() < b.Int(), a.Int() != b.Int()
+  case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    return a.Uint() < b.Uint(), a.Uint() != b.Uint()
+  case reflect.Float32, reflect.Float64:
+    return a.Float() < b.Float(), a.Float() != b.Float()
+  case reflect.Bool:
+    return !a.Bool() && b.Bool(), a.Bool() != b.Bool()
+  case reflect.String:
+    return a.String() < b.String(), a.String() != b.String()
+  }
+  return false, false
+}
+
+func (s *serializer) writeStruct(v reflect.Value, depth int) {
+  t := v.Type()
+  fmt.Fprintf(&s.sb, "%s{", t)
+  wrote := false
+  for i := 0; i < t.NumField(); i++ {
+    field := t.Field(i)
+    if field.PkgPath != "" && !s.opts.Unexported {
+      continue
+    }
+    if !wrote {
+      s.sb.WriteString("\n")
+      wrote = true
+    }
+    s.indent(depth + 1)
+    s.sb.WriteString(field.Name)
+    s.sb.WriteString(": ")
+    s.write(v.Field(i), depth + 1)
+    s.sb.WriteString(",\n")
+  }
+  if wrote {
+    s.indent(depth)
+  }
+  s.sb.WriteString("}")
+}
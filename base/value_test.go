@@ -0,0 +1,88 @@
+package base_test
+
+import (
+  "testing"
+  "time"
+
+  "github.com/jimmc/golden/base"
+)
+
+type valueNode struct {
+  Name string
+  Next *valueNode
+  Tags map[string]int
+  Data []byte
+  When time.Time
+  Any interface{}
+  hidden int
+}
+
+type valueLeaf struct {
+  Exported string
+  unexported []int
+}
+
+func exampleValue() *valueNode {
+  n := &valueNode{
+    Name: "first",
+    Tags: map[string]int{"z": 26, "a": 1, "m": 13},
+    Data: []byte("bytes"),
+    When: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+    Any: []float64{1.5, 2},
+    hidden: 7,
+  }
+  n.Next = &valueNode{Name: "second", Next: n}
+  return n
+}
+
+func TestGoldenValue(t *testing.T) {
+  base.AssertGoldenValue(t, "value", exampleValue())
+}
+
+func TestGoldenValueUnexported(t *testing.T) {
+  v := valueLeaf{"e", []int{1, 2}}
+  got := base.SerializeValue(v, base.SerializeOptions{Unexported: true})
+  want := `base_test.valueLeaf{
+  Exported: "e",
+  unexported: []int{
+    1,
+    2,
+  },
+}
+`
+  if got != want {
+    t.Errorf("SerializeValue with unexported: got %q, want %q", got, want)
+  }
+}
+
+func TestSerializeSliceCycle(t *testing.T) {
+  v := []interface{}{1, nil}
+  v[1] = v
+  got := base.SerializeValue(v, base.SerializeOptions{})
+  want := "[]interface {}{\n  1,\n  <cycle>,\n}\n"
+  if got != want {
+    t.Errorf("SerializeValue of self-referencing slice: got %q, want %q", got, want)
+  }
+}
+
+func TestSerializeSimple(t *testing.T) {
+  tests := []struct{
+    v interface{}
+    want string
+  }{
+    {nil, "nil\n"},
+    {42, "42\n"},
+    {"a\"b", "\"a\\\"b\"\n"},
+    {[]int{}, "[]int{}\n"},
+    {[]int(nil), "[]int(nil)\n"},
+    {map[int]bool{2: true, 1: false}, "map[int]bool{\n  1: false,\n  2: true,\n}\n"},
+    {map[int]int{10: 0, 2: 0, 1: 0, -3: 0}, "map[int]int{\n  -3: 0,\n  1: 0,\n  2: 0,\n  10: 0,\n}\n"},
+    {map[float64]int{10.5: 0, 2: 0}, "map[float64]int{\n  2: 0,\n  10.5: 0,\n}\n"},
+    {struct{}{}, "struct {}{}\n"},
+  }
+  for _, tc := range tests {
+    if got := base.SerializeValue(tc.v, base.SerializeOptions{}); got != tc.want {
+      t.Errorf("SerializeValue(%#v): got %q, want %q", tc.v, got, tc.want)
+    }
+  }
+}
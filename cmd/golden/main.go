@@ -0,0 +1,172 @@
+// Golden is a command to review the output files left behind by failing
+// golden tests and to accept them as the new golden files.
+//
+// Usage:
+//   golden [flags] [review] [dir ...]
+//   golden [flags] list [dir ...]
+//   golden [flags] accept-all [dir ...]
+//
+// The review command shows the diff for each output file that differs from
+// its golden file and asks whether to accept it (copy it to the golden file),
+// reject it (delete the output file), or skip it.
+// The list command prints the paths of the differing output files.
+// The accept-all command accepts all of them.
+// The directories default to the current directory and are searched
+// recursively.
+// Diffs are shown in color by default when stdout is a terminal.
+package main
+
+import (
+  "bufio"
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "strings"
+
+  "github.com/jimmc/golden/base"
+)
+
+var color = flag.Bool("color", isTerminal(os.Stdout), "show diffs in color (default true when stdout is a terminal)")
+
+const (
+  colorRed = "\033[31m"
+  colorGreen = "\033[32m"
+  colorCyan = "\033[36m"
+  colorReset = "\033[0m"
+)
+
+func main() {
+  flag.Usage = func() {
+    fmt.Fprintf(flag.CommandLine.Output(),
+        "usage: %s [flags] [review|list|accept-all] [dir ...]\n", os.Args[0])
+    flag.PrintDefaults()
+  }
+  flag.Parse()
+  args := flag.Args()
+  command := "review"
+  if len(args) > 0 {
+    switch args[0] {
+    case "review", "list", "accept-all":
+      command = args[0]
+      args = args[1:]
+    }
+  }
+  if len(args) == 0 {
+    args = []string{"."}
+  }
+  mismatches, err := base.FindMismatches(args...)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "golden: %v\n", err)
+    os.Exit(1)
+  }
+  switch command {
+  case "list":
+    err = list(os.Stdout, mismatches)
+  case "accept-all":
+    err = acceptAll(os.Stdout, mismatches)
+  default:
+    err = review(os.Stdin, os.Stdout, mismatches)
+  }
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "golden: %v\n", err)
+    os.Exit(1)
+  }
+}
+
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool {
+  info, err := f.Stat()
+  return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+// list prints the output file path of each mismatch.
+func list(w io.Writer, mismatches []*base.Mismatch) error {
+  for _, m := range mismatches {
+    if m.Missing {
+      fmt.Fprintf(w, "%s (no golden file)\n", m.OutPath)
+    } else {
+      fmt.Fprintln(w, m.OutPath)
+    }
+  }
+  return nil
+}
+
+// acceptAll accepts each mismatch.
+func acceptAll(w io.Writer, mismatches []*base.Mismatch) error {
+  for _, m := range mismatches {
+    if err := m.Accept(); err != nil {
+      return err
+    }
+    fmt.Fprintf(w, "accepted %s\n", m.OutPath)
+  }
+  return nil
+}
+
+// review shows the diff for each mismatch and asks what to do with it.
+func review(r io.Reader, w io.Writer, mismatches []*base.Mismatch) error {
+  if len(mismatches) == 0 {
+    fmt.Fprintln(w, "no differences")
+    return nil
+  }
+  in := bufio.NewReader(r)
+  for n, m := range mismatches {
+    diff, err := m.Diff()
+    if err != nil {
+      return err
+    }
+    fmt.Fprintf(w, "[%d/%d] %s vs %s\n", n + 1, len(mismatches), m.OutPath, m.GoldenPath)
+    if m.Missing {
+      fmt.Fprintln(w, "(no golden file)")
+    }
+    fmt.Fprint(w, colorDiff(diff))
+    for {
+      fmt.Fprint(w, "accept, reject, skip or quit? [a/r/s/q] ")
+      answer, err := in.ReadString('\n')
+      if err != nil && answer == "" {
+        if err == io.EOF {
+          fmt.Fprintln(w)
+          return nil
+        }
+        return err
+      }
+      switch strings.ToLower(strings.TrimSpace(answer)) {
+      case "a", "accept":
+        if err := m.Accept(); err != nil {
+          return err
+        }
+      case "r", "reject":
+        if err := m.Reject(); err != nil {
+          return err
+        }
+      case "s", "skip", "":
+      case "q", "quit":
+        return nil
+      default:
+        continue
+      }
+      break
+    }
+  }
+  return nil
+}
+
+// colorDiff adds terminal color codes to the lines of a diff from
+// base.TextDiff if the -color flag is set.
+func colorDiff(diff string) string {
+  if !*color {
+    return diff
+  }
+  lines := strings.SplitAfter(diff, "\n")
+  for i, line := range lines {
+    switch {
+    case strings.HasPrefix(line, "@@"):
+      lines[i] = colorCyan + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+    case strings.HasPrefix(line, "-"):
+      lines[i] = colorRed + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+    case strings.HasPrefix(line, "+"):
+      lines[i] = colorGreen + strings.TrimSuffix(line, "\n") + colorReset + "\n"
+    }
+  }
+  return strings.Join(lines, "")
+}
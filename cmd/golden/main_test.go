@@ -0,0 +1,179 @@
+package main
+
+import (
+  "bytes"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+// writeTestdata writes the files to a temporary testdata directory,
+// finds the mismatches in it, and returns the directory and mismatches.
+func writeTestdata(t *testing.T, files map[string]string) (string, []*base.Mismatch) {
+  t.Helper()
+  dir := filepath.Join(t.TempDir(), "testdata")
+  for name, content := range files {
+    path := filepath.Join(dir, name)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+      t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+  mismatches, err := base.FindMismatches(dir)
+  if err != nil {
+    t.Fatalf("FindMismatches: %v", err)
+  }
+  return dir, mismatches
+}
+
+// readFile returns the content of the file, or "<none>" if it does not exist.
+func readFile(t *testing.T, path string) string {
+  t.Helper()
+  content, err := ioutil.ReadFile(path)
+  if os.IsNotExist(err) {
+    return "<none>"
+  }
+  if err != nil {
+    t.Fatal(err)
+  }
+  return string(content)
+}
+
+var reviewFiles = map[string]string{
+  "a.out": "a\nnew\n",
+  "a.golden": "a\nold\n",
+  "b.out": "b\n",
+  "c.out": "c\nnew\n",
+  "c.golden": "c\nold\n",
+  "same.out": "same\n",
+  "same.golden": "same\n",
+}
+
+func TestReview(t *testing.T) {
+  *color = false
+  dir, mismatches := writeTestdata(t, reviewFiles)
+  var out bytes.Buffer
+  // Answer an unknown command, then accept a, reject b, and skip c.
+  if err := review(strings.NewReader("x\na\nr\n\n"), &out, mismatches); err != nil {
+    t.Fatalf("review: %v", err)
+  }
+  for path, want := range map[string]string{
+    "a.golden": "a\nnew\n",
+    "a.out": "a\nnew\n",
+    "b.out": "<none>",
+    "b.golden": "<none>",
+    "c.golden": "c\nold\n",
+    "c.out": "c\nnew\n",
+  } {
+    if got := readFile(t, filepath.Join(dir, path)); got != want {
+      t.Errorf("%s: got %q, want %q", path, got, want)
+    }
+  }
+  for _, want := range []string{
+    "[1/3] " + filepath.Join(dir, "a.out") + " vs " + filepath.Join(dir, "a.golden") + "\n",
+    "-old\n+new\n",
+    "[2/3] " + filepath.Join(dir, "b.out"),
+    "(no golden file)\n",
+    "[3/3] " + filepath.Join(dir, "c.out"),
+  } {
+    if !strings.Contains(out.String(), want) {
+      t.Errorf("review output does not contain %q:\n%s", want, out.String())
+    }
+  }
+  if strings.Contains(out.String(), "\033[") {
+    t.Errorf("review output has color codes with -color=false:\n%s", out.String())
+  }
+}
+
+func TestReviewQuit(t *testing.T) {
+  *color = false
+  dir, mismatches := writeTestdata(t, reviewFiles)
+  var out bytes.Buffer
+  if err := review(strings.NewReader("q\n"), &out, mismatches); err != nil {
+    t.Fatalf("review: %v", err)
+  }
+  if got, want := readFile(t, filepath.Join(dir, "a.golden")), "a\nold\n"; got != want {
+    t.Errorf("a.golden: got %q, want %q", got, want)
+  }
+  if strings.Contains(out.String(), "[2/3]") {
+    t.Errorf("review continued after quit:\n%s", out.String())
+  }
+
+  // Running out of input also stops the review.
+  out.Reset()
+  if err := review(strings.NewReader(""), &out, mismatches); err != nil {
+    t.Fatalf("review with no input: %v", err)
+  }
+}
+
+func TestReviewNoDifferences(t *testing.T) {
+  _, mismatches := writeTestdata(t, map[string]string{"same.out": "x\n", "same.golden": "x\n"})
+  var out bytes.Buffer
+  if err := review(strings.NewReader(""), &out, mismatches); err != nil {
+    t.Fatalf("review: %v", err)
+  }
+  if got, want := out.String(), "no differences\n"; got != want {
+    t.Errorf("review: got %q, want %q", got, want)
+  }
+}
+
+func TestList(t *testing.T) {
+  dir, mismatches := writeTestdata(t, reviewFiles)
+  var out bytes.Buffer
+  if err := list(&out, mismatches); err != nil {
+    t.Fatalf("list: %v", err)
+  }
+  want := filepath.Join(dir, "a.out") + "\n" +
+      filepath.Join(dir, "b.out") + " (no golden file)\n" +
+      filepath.Join(dir, "c.out") + "\n"
+  if got := out.String(); got != want {
+    t.Errorf("list: got %q, want %q", got, want)
+  }
+}
+
+func TestAcceptAll(t *testing.T) {
+  dir, mismatches := writeTestdata(t, reviewFiles)
+  var out bytes.Buffer
+  if err := acceptAll(&out, mismatches); err != nil {
+    t.Fatalf("acceptAll: %v", err)
+  }
+  for path, want := range map[string]string{
+    "a.golden": "a\nnew\n",
+    "b.golden": "b\n",
+    "c.golden": "c\nnew\n",
+  } {
+    if got := readFile(t, filepath.Join(dir, path)); got != want {
+      t.Errorf("%s: got %q, want %q", path, got, want)
+    }
+  }
+  mismatches, err := base.FindMismatches(dir)
+  if err != nil {
+    t.Fatalf("FindMismatches: %v", err)
+  }
+  if len(mismatches) != 0 {
+    t.Errorf("FindMismatches after acceptAll: got %d mismatches, want 0", len(mismatches))
+  }
+}
+
+func TestColorDiff(t *testing.T) {
+  diff := "@@ golden line 1, out line 1 @@\n-old\n+new\n same\n"
+  *color = false
+  if got := colorDiff(diff); got != diff {
+    t.Errorf("colorDiff without color: got %q, want %q", got, diff)
+  }
+  *color = true
+  defer func() { *color = false }()
+  want := colorCyan + "@@ golden line 1, out line 1 @@" + colorReset + "\n" +
+      colorRed + "-old" + colorReset + "\n" +
+      colorGreen + "+new" + colorReset + "\n" +
+      " same\n"
+  if got := colorDiff(diff); got != want {
+    t.Errorf("colorDiff with color: got %q, want %q", got, want)
+  }
+}
@@ -5,6 +5,7 @@ import (
   "database/sql"
   "io/ioutil"
 
+  "github.com/jimmc/golden/base"
   _ "github.com/mattn/go-sqlite3"       // driver name: sqlite3
 )
 
@@ -20,6 +21,7 @@ func EmptyDb() (*sql.DB, error) {
 
 // LoadSetupFile reads and executes SQL commands from the specified file.
 func LoadSetupFile(db *sql.DB, filename string) error {
+  base.RecordUsed(filename)
   setupSql, err := ioutil.ReadFile(filename)
   if err != nil {
     return err
@@ -34,6 +36,7 @@ func LoadSetupString(db *sql.DB, setupSql string) error {
 
 // DbWithSetupFile creates a new database and executes SQL commands from the given file.
 func DbWithSetupFile(filename string) (*sql.DB, error) {
+  base.RecordUsed(filename)
   setupSql, err := ioutil.ReadFile(filename)
   if err != nil {
     return nil, err
@@ -0,0 +1,121 @@
+package db
+
+import (
+  "database/sql"
+  "encoding/hex"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// DumpTables writes the contents of the named tables to w in a stable
+// text format suitable for comparing to a golden file.
+// If no tables are named, it dumps all tables, in sorted order by name.
+// Each table is written as a header line giving the table name,
+// a line with the column names, then one line per row, with the rows sorted
+// by all of the columns in order. Values are written as SQL literals.
+func DumpTables(db *sql.DB, w io.Writer, tables ...string) error {
+  if len(tables) == 0 {
+    var err error
+    tables, err = TableNames(db)
+    if err != nil {
+      return err
+    }
+  }
+  for i, table := range tables {
+    if i > 0 {
+      if _, err := io.WriteString(w, "\n"); err != nil {
+        return err
+      }
+    }
+    if err := DumpTable(db, w, table); err != nil {
+      return fmt.Errorf("error dumping table %s: %v", table, err)
+    }
+  }
+  return nil
+}
+
+// DumpTable writes the contents of one table to w as described in DumpTables.
+func DumpTable(db *sql.DB, w io.Writer, table string) error {
+  columns, err := tableColumns(db, table)
+  if err != nil {
+    return err
+  }
+  order := make([]string, len(columns))
+  for i := range columns {
+    order[i] = strconv.Itoa(i + 1)
+  }
+  query := "SELECT * FROM " + QuoteIdentifier(table)
+  if len(order) > 0 {
+    query += " ORDER BY " + strings.Join(order, ", ")
+  }
+  rows, err := db.Query(query)
+  if err != nil {
+    return err
+  }
+  defer rows.Close()
+  fmt.Fprintf(w, "table %s\n", table)
+  fmt.Fprintf(w, "  %s\n", strings.Join(columns, " | "))
+  values := make([]interface{}, len(columns))
+  targets := make([]interface{}, len(columns))
+  for i := range values {
+    targets[i] = &values[i]
+  }
+  for rows.Next() {
+    if err := rows.Scan(targets...); err != nil {
+      return err
+    }
+    literals := make([]string, len(values))
+    for i, value := range values {
+      literals[i] = sqlLiteral(value)
+    }
+    if _, err := fmt.Fprintf(w, "  %s\n", strings.Join(literals, " | ")); err != nil {
+      return err
+    }
+  }
+  return rows.Err()
+}
+
+// TableNames returns the names of all of the user tables in the database,
+// in sorted order.
+func TableNames(db *sql.DB) ([]string, error) {
+  schema, err := ReadSchema(db)
+  if err != nil {
+    return nil, err
+  }
+  return schema.TableNames(), nil
+}
+
+// tableColumns returns the names of the columns of a table.
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+  rows, err := db.Query("SELECT * FROM " + QuoteIdentifier(table) + " LIMIT 0")
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  return rows.Columns()
+}
+
+// sqlLiteral formats a value scanned from the database as an SQL literal.
+func sqlLiteral(value interface{}) string {
+  switch v := value.(type) {
+  case nil:
+    return "NULL"
+  case []byte:
+    return "x'" + hex.EncodeToString(v) + "'"
+  case string:
+    return QuoteString(v)
+  case time.Time:
+    return QuoteString(v.UTC().Format(time.RFC3339Nano))
+  case float64:
+    return strconv.FormatFloat(v, 'g', -1, 64)
+  case bool:
+    if v {
+      return "TRUE"
+    }
+    return "FALSE"
+  }
+  return fmt.Sprint(value)
+}
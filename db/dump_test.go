@@ -0,0 +1,71 @@
+package db_test
+
+import (
+  "database/sql"
+  "errors"
+  "io"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+// modify is used as a function under test that changes the database.
+func modify(d *sql.DB, w io.Writer) error {
+  if _, err := d.Exec("UPDATE test SET s = 'changed' WHERE n = 2;"); err != nil {
+    return err
+  }
+  if _, err := d.Exec("DELETE FROM test WHERE s IS NULL;"); err != nil {
+    return err
+  }
+  return example(d, w)
+}
+
+func TestDumpDb(t *testing.T) {
+  r := db.NewTester("dump", modify)
+  r.DumpDb = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestDumpDbTables(t *testing.T) {
+  r := db.NewTester("dump-tables", modify)
+  r.SetupBaseName = "dump"
+  r.GoldenBaseName = "dump"
+  r.DumpDb = true
+  r.DumpTableNames = []string{"other"}
+  r.DbGoldenPath = "testdata/dump-other.dbgolden"
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestDumpDbMismatch(t *testing.T) {
+  disableUpdate(t)
+  r := db.NewTester("dump-no-match", modify)
+  r.SetupBaseName = "dump"
+  r.GoldenBaseName = "dump"
+  r.DumpDb = true
+  r.DbGoldenPath = "testdata/dump-other.dbgolden"
+  if err := base.RunOne(r); err == nil {
+    t.Fatalf("Expected error due to database dump mismatch")
+  }
+}
+
+func TestAssertReportsAllMismatches(t *testing.T) {
+  disableUpdate(t)
+  r := db.NewTester("dump-all-mismatches", func(d *sql.DB, w io.Writer) error {
+    _, err := io.WriteString(w, "unexpected output\n")
+    return err
+  })
+  r.SetupBaseName = "dump"
+  r.GoldenBaseName = "dump"
+  r.DumpDb = true
+  r.DbGoldenPath = "testdata/dump-other.dbgolden"
+  err := base.RunOne(r)
+  var errs base.MultiError
+  if !errors.As(err, &errs) || len(errs) != 2 {
+    t.Fatalf("Expected output and database dump mismatches, got %v", err)
+  }
+}
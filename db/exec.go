@@ -2,20 +2,28 @@ package db
 
 import (
   "database/sql"
-  "regexp"
+  "fmt"
   "strings"
 )
 
+// ExpectErrorPrefix starts a comment line in a segment that marks the segment
+// as expected to fail. Any text after the prefix and an optional colon
+// must appear in the error message.
+const ExpectErrorPrefix = "# expect-error"
+
+// Execer is implemented by both *sql.DB and *sql.Tx, so that statements
+// can be executed either directly or within a transaction.
+type Execer interface {
+  Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // ExecMulti executes multiple sql statements from a string.
-// It strips out all carriage returns, breaks the string into segments at double-newlines, removes lines
+// It breaks the string into segments with SplitSegments, removes lines
 // starting with a "#" (as comments), and separately executes
 // each segment. If any segment returns an error, it stop executing
 // and returns that error.
-func ExecMulti(db *sql.DB, sql string) error {
-  re := regexp.MustCompile("\r")
-  sql = re.ReplaceAllString(sql, "")
-  segments := strings.Split(sql, "\n\n")
-  for _, segment := range segments {
+func ExecMulti(db Execer, sql string) error {
+  for _, segment := range SplitSegments(sql) {
     if err := ExecSegment(db, segment); err != nil {
       return err
     }
@@ -23,17 +31,48 @@ func ExecMulti(db *sql.DB, sql string) error {
   return nil
 }
 
+// SplitSegments strips out all carriage returns and splits the string into
+// segments at double-newlines. A line holding only spaces or tabs does not
+// separate segments. This is how both setup files and query files are
+// split into statements.
+func SplitSegments(sql string) []string {
+  sql = strings.ReplaceAll(sql, "\r", "")
+  return strings.Split(sql, "\n\n")
+}
+
 // ExecSegment executes a single sql statement from a string.
 // It removes lines starting with "#" (as comments).
-func ExecSegment(db *sql.DB, segment string) error {
+// If the segment includes a comment line such as
+//   # expect-error: UNIQUE constraint
+// then the statement must fail with an error message that contains the text
+// after the colon, or with any error if there is no text;
+// ExecSegment returns an error if the statement succeeds or fails differently.
+func ExecSegment(db Execer, segment string) error {
   lines := strings.Split(segment, "\n")
   sqlLines := make([]string, 0)
+  expectError := false
+  expectedMessage := ""
   for _, line := range lines {
-    if !strings.HasPrefix(line, "#") {
+    if strings.HasPrefix(line, ExpectErrorPrefix) {
+      expectError = true
+      expectedMessage = strings.TrimSpace(strings.TrimPrefix(
+          strings.TrimPrefix(line, ExpectErrorPrefix), ":"))
+    } else if !strings.HasPrefix(line, "#") {
       sqlLines = append(sqlLines, line)
     }
   }
   segment = strings.Join(sqlLines, "\n")
   _, err := db.Exec(segment)
-  return err
+  if !expectError {
+    return err
+  }
+  if err == nil {
+    return fmt.Errorf("expected error containing %q but statement succeeded: %s",
+        expectedMessage, strings.TrimSpace(segment))
+  }
+  if !strings.Contains(err.Error(), expectedMessage) {
+    return fmt.Errorf("expected error containing %q but got %q from statement: %s",
+        expectedMessage, err.Error(), strings.TrimSpace(segment))
+  }
+  return nil
 }
@@ -126,3 +126,64 @@ func TestExecErrors(t *testing.T) {
     t.Errorf("Expected error for invalid sql")
   }
 }
+
+func TestExpectError(t *testing.T) {
+  setup := `
+CREATE table test(n int unique, s string);
+INSERT into test(n, s) values(1, 'a');
+
+# The unique constraint rejects a duplicate.
+# expect-error: UNIQUE constraint
+INSERT into test(n, s) values(1, 'b');
+
+# expect-error
+INSERT into nosuchtable(n) values(1);
+`
+  query := "SELECT n, s from test order by n;"
+  expectedResult := []*eTestRow{
+    &eTestRow{1, "a"},
+  }
+
+  rows, err := setupAndCollectETestRows(setup, query)
+  if err != nil {
+    t.Fatalf("Error collecting rows: %v", err)
+  }
+  if got, want := rows, expectedResult; !reflect.DeepEqual(got, want) {
+    t.Errorf("Results array, got %v, want %v", got, want)
+  }
+}
+
+func TestExpectErrorButSucceeded(t *testing.T) {
+  setup := `
+CREATE table test(n int unique, s string);
+
+# expect-error: UNIQUE constraint
+INSERT into test(n, s) values(1, 'a');
+`
+  if _, err := setupAndCollectETestRows(setup, "SELECT n, s from test;"); err == nil {
+    t.Errorf("Expected error when statement marked expect-error succeeds")
+  }
+}
+
+func TestExpectErrorWrongMessage(t *testing.T) {
+  setup := `
+# expect-error: UNIQUE constraint
+INSERT into nosuchtable(n) values(1);
+`
+  if _, err := setupAndCollectETestRows(setup, "SELECT 1;"); err == nil {
+    t.Errorf("Expected error when statement fails with a different message")
+  }
+}
+
+func TestSplitSegments(t *testing.T) {
+  segments := goldendb.SplitSegments("a;\r\n\r\nb;\n  \t\nc;\nd;")
+  want := []string{"a;", "b;\n  \t\nc;\nd;"}
+  if len(segments) != len(want) {
+    t.Fatalf("SplitSegments: got %q, want %q", segments, want)
+  }
+  for i := range want {
+    if segments[i] != want[i] {
+      t.Errorf("SplitSegments[%d]: got %q, want %q", i, segments[i], want[i])
+    }
+  }
+}
@@ -0,0 +1,336 @@
+package db
+
+import (
+  "bytes"
+  "database/sql"
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "os"
+  "path"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+
+  "github.com/jimmc/golden/base"
+  "gopkg.in/yaml.v3"
+)
+
+// FixtureRows is the data for one table in a fixture file.
+// Each row maps column names to values.
+type FixtureRows []map[string]interface{}
+
+// FixtureTable is the data for one named table in a fixture file.
+type FixtureTable struct {
+  Table string
+  Rows FixtureRows
+}
+
+// LoadFixtures loads all of the fixture files for basename in dir.
+// It loads each file matching <basename>.<table>.csv into the named table,
+// in sorted order by file name, then loads <basename>.json, <basename>.yaml
+// and <basename>.yml if they exist.
+// Missing fixture files are not an error.
+// The tables must already exist, typically created by a setup file.
+func LoadFixtures(db *sql.DB, dir, basename string) error {
+  csvPaths, err := filepath.Glob(path.Join(dir, basename + ".*.csv"))
+  if err != nil {
+    return err
+  }
+  sort.Strings(csvPaths)
+  for _, csvPath := range csvPaths {
+    table := strings.TrimSuffix(strings.TrimPrefix(path.Base(csvPath), basename + "."), ".csv")
+    if err := LoadCSVFile(db, table, csvPath); err != nil {
+      return err
+    }
+  }
+  loaders := []struct{
+    extension string
+    load func(*sql.DB, string) error
+  }{
+    {"json", LoadJSONFile},
+    {"yaml", LoadYAMLFile},
+    {"yml", LoadYAMLFile},
+  }
+  for _, loader := range loaders {
+    filename := path.Join(dir, basename + "." + loader.extension)
+    if _, err := os.Stat(filename); os.IsNotExist(err) {
+      continue
+    }
+    if err := loader.load(db, filename); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// LoadCSVFile loads the data from the given CSV file into the table.
+// The first line of the file gives the column names.
+// An empty field is loaded as NULL unless the column is a text column.
+func LoadCSVFile(db *sql.DB, table, filename string) error {
+  base.RecordUsed(filename)
+  f, err := os.Open(filename)
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+  if err := LoadCSV(db, table, f); err != nil {
+    return fmt.Errorf("error loading CSV file %s: %v", filename, err)
+  }
+  return nil
+}
+
+// LoadCSV loads CSV data from the reader into the table.
+// See LoadCSVFile.
+func LoadCSV(db *sql.DB, table string, r io.Reader) error {
+  cr := csv.NewReader(r)
+  columns, err := cr.Read()
+  if err == io.EOF {
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+  rows := make(FixtureRows, 0)
+  for {
+    record, err := cr.Read()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return err
+    }
+    row := make(map[string]interface{})
+    for i, value := range record {
+      row[columns[i]] = value
+    }
+    rows = append(rows, row)
+  }
+  return LoadRows(db, table, rows)
+}
+
+// LoadJSONFile loads the data from the given JSON file.
+// The file must contain an object whose keys are table names and whose
+// values are arrays of objects, each of which maps column names to values.
+// Tables are loaded in the order they appear in the file.
+func LoadJSONFile(db *sql.DB, filename string) error {
+  base.RecordUsed(filename)
+  data, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return err
+  }
+  tables, err := ParseJSONFixture(data)
+  if err == nil {
+    err = LoadTables(db, tables)
+  }
+  if err != nil {
+    return fmt.Errorf("error loading JSON file %s: %v", filename, err)
+  }
+  return nil
+}
+
+// ParseJSONFixture parses JSON fixture data as described in LoadJSONFile.
+func ParseJSONFixture(data []byte) ([]*FixtureTable, error) {
+  dec := json.NewDecoder(bytes.NewReader(data))
+  dec.UseNumber()
+  if tok, err := dec.Token(); err != nil {
+    return nil, err
+  } else if tok != json.Delim('{') {
+    return nil, fmt.Errorf("expected JSON object keyed by table name, got %v", tok)
+  }
+  tables := make([]*FixtureTable, 0)
+  for dec.More() {
+    tok, err := dec.Token()
+    if err != nil {
+      return nil, err
+    }
+    table := &FixtureTable{Table: tok.(string)}
+    if err := dec.Decode(&table.Rows); err != nil {
+      return nil, fmt.Errorf("error decoding rows for table %s: %v", table.Table, err)
+    }
+    tables = append(tables, table)
+  }
+  return tables, nil
+}
+
+// LoadYAMLFile loads the data from the given YAML file.
+// The file must contain a mapping whose keys are table names and whose
+// values are sequences of mappings, each of which maps column names to values.
+// Tables are loaded in the order they appear in the file.
+func LoadYAMLFile(db *sql.DB, filename string) error {
+  base.RecordUsed(filename)
+  data, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return err
+  }
+  tables, err := ParseYAMLFixture(data)
+  if err == nil {
+    err = LoadTables(db, tables)
+  }
+  if err != nil {
+    return fmt.Errorf("error loading YAML file %s: %v", filename, err)
+  }
+  return nil
+}
+
+// ParseYAMLFixture parses YAML fixture data as described in LoadYAMLFile.
+func ParseYAMLFixture(data []byte) ([]*FixtureTable, error) {
+  var doc yaml.Node
+  if err := yaml.Unmarshal(data, &doc); err != nil {
+    return nil, err
+  }
+  tables := make([]*FixtureTable, 0)
+  if len(doc.Content) == 0 {
+    return tables, nil
+  }
+  top := doc.Content[0]
+  if top.Kind != yaml.MappingNode {
+    return nil, fmt.Errorf("expected YAML mapping keyed by table name at line %d", top.Line)
+  }
+  for i := 0; i + 1 < len(top.Content); i += 2 {
+    table := &FixtureTable{Table: top.Content[i].Value}
+    if err := top.Content[i+1].Decode(&table.Rows); err != nil {
+      return nil, fmt.Errorf("error decoding rows for table %s: %v", table.Table, err)
+    }
+    tables = append(tables, table)
+  }
+  return tables, nil
+}
+
+// LoadTables loads each of the given tables in order.
+func LoadTables(db *sql.DB, tables []*FixtureTable) error {
+  for _, table := range tables {
+    if err := LoadRows(db, table.Table, table.Rows); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// LoadRows inserts the rows into the table using prepared statements
+// within a single transaction.
+// Values are converted to the declared types of the table's columns.
+// A column that is not present in a row is left to its default value.
+func LoadRows(db *sql.DB, table string, rows FixtureRows) error {
+  if len(rows) == 0 {
+    return nil
+  }
+  columnTypes, err := tableColumnTypes(db, table)
+  if err != nil {
+    return err
+  }
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+  stmts := make(map[string]*sql.Stmt)
+  for n, row := range rows {
+    columns := make([]string, 0, len(row))
+    for column := range row {
+      columns = append(columns, column)
+    }
+    sort.Strings(columns)
+    args := make([]interface{}, len(columns))
+    for i, column := range columns {
+      columnType, ok := columnTypes[strings.ToLower(column)]
+      if !ok {
+        return fmt.Errorf("table %s row %d: no such column %s", table, n + 1, column)
+      }
+      arg, err := convertFixtureValue(row[column], columnType)
+      if err != nil {
+        return fmt.Errorf("table %s row %d column %s: %v", table, n + 1, column, err)
+      }
+      args[i] = arg
+    }
+    key := strings.Join(columns, ",")
+    stmt := stmts[key]
+    if stmt == nil {
+      stmt, err = tx.Prepare(insertStatement(table, columns))
+      if err != nil {
+        return fmt.Errorf("error preparing insert for table %s: %v", table, err)
+      }
+      defer stmt.Close()
+      stmts[key] = stmt
+    }
+    if _, err := stmt.Exec(args...); err != nil {
+      return fmt.Errorf("table %s row %d: %v", table, n + 1, err)
+    }
+  }
+  return tx.Commit()
+}
+
+// tableColumnTypes returns a map of the lower-cased column names of a table
+// to the upper-cased database type names of those columns.
+func tableColumnTypes(db *sql.DB, table string) (map[string]string, error) {
+  rows, err := db.Query("SELECT * FROM " + QuoteIdentifier(table) + " LIMIT 0")
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  cts, err := rows.ColumnTypes()
+  if err != nil {
+    return nil, err
+  }
+  columnTypes := make(map[string]string)
+  for _, ct := range cts {
+    columnTypes[strings.ToLower(ct.Name())] = strings.ToUpper(ct.DatabaseTypeName())
+  }
+  return columnTypes, nil
+}
+
+// insertStatement returns an INSERT statement with placeholders
+// for the given table and columns.
+func insertStatement(table string, columns []string) string {
+  quoted := make([]string, len(columns))
+  placeholders := make([]string, len(columns))
+  for i, column := range columns {
+    quoted[i] = QuoteIdentifier(column)
+    placeholders[i] = "?"
+  }
+  return fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)",
+      QuoteIdentifier(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+}
+
+// QuoteIdentifier returns name as a double-quoted SQL identifier.
+func QuoteIdentifier(name string) string {
+  return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// convertFixtureValue converts a value read from a fixture file
+// to a value suitable for a column of the given database type.
+func convertFixtureValue(value interface{}, typeName string) (interface{}, error) {
+  if value == nil {
+    return nil, nil
+  }
+  s := fmt.Sprint(value)
+  switch {
+  case strings.Contains(typeName, "INT"):
+    if s == "" {
+      return nil, nil
+    }
+    if b, ok := value.(bool); ok {
+      if b {
+        return int64(1), nil
+      }
+      return int64(0), nil
+    }
+    return strconv.ParseInt(s, 10, 64)
+  case strings.Contains(typeName, "REAL"), strings.Contains(typeName, "FLOA"),
+      strings.Contains(typeName, "DOUB"), strings.Contains(typeName, "NUMERIC"),
+      strings.Contains(typeName, "DECIMAL"):
+    if s == "" {
+      return nil, nil
+    }
+    return strconv.ParseFloat(s, 64)
+  case strings.Contains(typeName, "BOOL"):
+    if s == "" {
+      return nil, nil
+    }
+    return strconv.ParseBool(s)
+  }
+  return s, nil
+}
@@ -0,0 +1,86 @@
+package db_test
+
+import (
+  "database/sql"
+  "fmt"
+  "io"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func listFixtureTables(db *sql.DB, w io.Writer) error {
+  queries := []struct{
+    table string
+    sql string
+  }{
+    {"test", "SELECT 'n=' || ifnull(n, '<nil>'), 's=' || s FROM test ORDER BY rowid;"},
+    {"item", "SELECT 'id=' || id, 'name=' || name, 'price=' || ifnull(price, '<nil>'), 'active=' || active FROM item ORDER BY id;"},
+    {"tag", "SELECT 'id=' || id, 'label=' || ifnull(label, '<nil>') FROM tag ORDER BY id;"},
+  }
+  for _, q := range queries {
+    rows, err := db.Query(q.sql)
+    if err != nil {
+      return err
+    }
+    columns, err := rows.Columns()
+    if err != nil {
+      rows.Close()
+      return err
+    }
+    values := make([]string, len(columns))
+    targets := make([]interface{}, len(columns))
+    for i := range values {
+      targets[i] = &values[i]
+    }
+    for rows.Next() {
+      if err := rows.Scan(targets...); err != nil {
+        rows.Close()
+        return err
+      }
+      fmt.Fprintf(w, "%s:", q.table)
+      for _, value := range values {
+        fmt.Fprintf(w, " %s", value)
+      }
+      fmt.Fprintln(w)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func TestFixtures(t *testing.T) {
+  r := db.NewTester("fixtures", listFixtureTables)
+  r.LoadFixtures = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestFixtureBadColumn(t *testing.T) {
+  d, err := db.DbWithSetupString("CREATE table test(n int, s string);")
+  if err != nil {
+    t.Fatalf("DbWithSetupString: %v", err)
+  }
+  defer d.Close()
+  rows := db.FixtureRows{{"n": 1, "nosuchcolumn": "x"}}
+  if err := db.LoadRows(d, "test", rows); err == nil {
+    t.Errorf("Expected error for unknown column")
+  }
+}
+
+func TestFixtureBadValue(t *testing.T) {
+  d, err := db.DbWithSetupString("CREATE table test(n int, s string);")
+  if err != nil {
+    t.Fatalf("DbWithSetupString: %v", err)
+  }
+  defer d.Close()
+  rows := db.FixtureRows{{"n": "not-a-number"}}
+  if err := db.LoadRows(d, "test", rows); err == nil {
+    t.Errorf("Expected error for value that does not match column type")
+  }
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+  "context"
+  "database/sql"
+  "encoding/csv"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+  "time"
+  "unicode/utf8"
+)
+
+// Format specifies how WriteRows formats query results.
+type Format int
+
+const (
+  // FormatTable writes an aligned text table with a header line.
+  FormatTable Format = iota
+  // FormatCSV writes CSV with a header line. NULL is written as an empty field.
+  FormatCSV
+  // FormatJSON writes one JSON object per row, with keys in column order.
+  FormatJSON
+  // FormatMarkdown writes a Markdown table.
+  FormatMarkdown
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+  switch f {
+  case FormatTable:
+    return "table"
+  case FormatCSV:
+    return "csv"
+  case FormatJSON:
+    return "json"
+  case FormatMarkdown:
+    return "markdown"
+  }
+  return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// WriteQuery runs the query and writes the results to w in the given format.
+func WriteQuery(db *sql.DB, w io.Writer, format Format, query string, args ...interface{}) error {
+  return WriteQueryContext(context.Background(), db, w, format, query, args...)
+}
+
+// WriteQueryContext is like WriteQuery, but runs the query with ctx.
+func WriteQueryContext(ctx context.Context, db *sql.DB, w io.Writer, format Format, query string, args ...interface{}) error {
+  rows, err := db.QueryContext(ctx, query, args...)
+  if err != nil {
+    return err
+  }
+  defer rows.Close()
+  return WriteRows(w, rows, format)
+}
+
+// WriteRows reads all of the rows and writes them to w in the given format.
+// Values are formatted deterministically: NULL is written as NULL
+// (except in CSV and JSON), byte slices are written as strings if they are
+// valid UTF-8 and otherwise as hex in the form x'0a1b', and time values
+// are converted to UTC and written in RFC 3339 format.
+func WriteRows(w io.Writer, rows *sql.Rows, format Format) error {
+  columns, err := rows.Columns()
+  if err != nil {
+    return err
+  }
+  values := make([]interface{}, len(columns))
+  targets := make([]interface{}, len(columns))
+  for i := range values {
+    targets[i] = &values[i]
+  }
+  records := make([][]interface{}, 0)
+  for rows.Next() {
+    if err := rows.Scan(targets...); err != nil {
+      return err
+    }
+    record := make([]interface{}, len(values))
+    for i, value := range values {
+      record[i] = normalizeValue(value)
+    }
+    records = append(records, record)
+  }
+  if err := rows.Err(); err != nil {
+    return err
+  }
+  switch format {
+  case FormatTable:
+    return writeTable(w, columns, records)
+  case FormatCSV:
+    return writeCSV(w, columns, records)
+  case FormatJSON:
+    return writeJSON(w, columns, records)
+  case FormatMarkdown:
+    return writeMarkdown(w, columns, records)
+  }
+  return fmt.Errorf("unknown format %v", format)
+}
+
+// normalizeValue converts a value scanned from the database into
+// nil, string, int64, float64, or bool.
+func normalizeValue(value interface{}) interface{} {
+  switch v := value.(type) {
+  case []byte:
+    if utf8.Valid(v) {
+      return string(v)
+    }
+    return "x'" + hex.EncodeToString(v) + "'"
+  case time.Time:
+    return v.UTC().Format(time.RFC3339Nano)
+  }
+  return value
+}
+
+// textValue formats a normalized value as text.
+func textValue(value interface{}) string {
+  switch v := value.(type) {
+  case nil:
+    return "NULL"
+  case float64:
+    return strconv.FormatFloat(v, 'g', -1, 64)
+  }
+  return fmt.Sprint(value)
+}
+
+func writeTable(w io.Writer, columns []string, records [][]interface{}) error {
+  widths := make([]int, len(columns))
+  for i, column := range columns {
+    widths[i] = utf8.RuneCountInString(column)
+  }
+  cells := make([][]string, len(records))
+  for r, record := range records {
+    cells[r] = make([]string, len(record))
+    for i, value := range record {
+      cells[r][i] = textValue(value)
+      if n := utf8.RuneCountInString(cells[r][i]); n > widths[i] {
+        widths[i] = n
+      }
+    }
+  }
+  writeLine := func(fields []string) error {
+    padded := make([]string, len(fields))
+    for i, field := range fields {
+      padded[i] = field + strings.Repeat(" ", widths[i] - utf8.RuneCountInString(field))
+    }
+    _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(padded, "  "), " "))
+    return err
+  }
+  if err := writeLine(columns); err != nil {
+    return err
+  }
+  dashes := make([]string, len(columns))
+  for i := range columns {
+    dashes[i] = strings.Repeat("-", widths[i])
+  }
+  if err := writeLine(dashes); err != nil {
+    return err
+  }
+  for _, fields := range cells {
+    if err := writeLine(fields); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func writeCSV(w io.Writer, columns []string, records [][]interface{}) error {
+  cw := csv.NewWriter(w)
+  if err := cw.Write(columns); err != nil {
+    return err
+  }
+  for _, record := range records {
+    fields := make([]string, len(record))
+    for i, value := range record {
+      if value != nil {
+        fields[i] = textValue(value)
+      }
+    }
+    if err := cw.Write(fields); err != nil {
+      return err
+    }
+  }
+  cw.Flush()
+  return cw.Error()
+}
+
+func writeJSON(w io.Writer, columns []string, records [][]interface{}) error {
+  for _, record := range records {
+    var sb strings.Builder
+    sb.WriteString("{")
+    for i, value := range record {
+      if i > 0 {
+        sb.WriteString(",")
+      }
+      key, err := json.Marshal(columns[i])
+      if err != nil {
+        return err
+      }
+      encoded, err := json.Marshal(value)
+      if err != nil {
+        return err
+      }
+      sb.Write(key)
+      sb.WriteString(":")
+      sb.Write(encoded)
+    }
+    sb.WriteString("}\n")
+    if _, err := io.WriteString(w, sb.String()); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func writeMarkdown(w io.Writer, columns []string, records [][]interface{}) error {
+  escape := func(s string) string {
+    s = strings.ReplaceAll(s, "|", `\|`)
+    return strings.ReplaceAll(s, "\n", "<br>")
+  }
+  writeLine := func(fields []string) error {
+    _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(fields, " | "))
+    return err
+  }
+  header := make([]string, len(columns))
+  separator := make([]string, len(columns))
+  for i, column := range columns {
+    header[i] = escape(column)
+    separator[i] = "---"
+  }
+  if err := writeLine(header); err != nil {
+    return err
+  }
+  if err := writeLine(separator); err != nil {
+    return err
+  }
+  for _, record := range records {
+    fields := make([]string, len(record))
+    for i, value := range record {
+      fields[i] = escape(textValue(value))
+    }
+    if err := writeLine(fields); err != nil {
+      return err
+    }
+  }
+  return nil
+}
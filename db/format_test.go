@@ -0,0 +1,35 @@
+package db_test
+
+import (
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func TestFormats(t *testing.T) {
+  formats := []db.Format{db.FormatTable, db.FormatCSV, db.FormatJSON, db.FormatMarkdown}
+  for _, format := range formats {
+    r := db.NewQueryTester("format-" + format.String(), format, "SELECT n, s, f, b, t FROM test ORDER BY n;")
+    r.SetupBaseName = "format"
+    if err := base.RunOne(r); err != nil {
+      t.Errorf("Error in RunOne for format %v: %v", format, err)
+    }
+  }
+}
+
+func TestQueryTester(t *testing.T) {
+  r := db.NewQueryTester("example-query", db.FormatTable, "SELECT s, n FROM test WHERE n > ? ORDER BY s;", 1)
+  r.SetupBaseName = "example"
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestWriteQueryError(t *testing.T) {
+  r := db.NewQueryTester("query-error", db.FormatTable, "SELECT * FROM nosuchtable;")
+  r.SetupBaseName = "example"
+  if err := base.RunOne(r); err == nil {
+    t.Fatalf("Expected error for query on nonexistent table")
+  }
+}
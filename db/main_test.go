@@ -0,0 +1,20 @@
+package db_test
+
+import (
+  "os"
+  "testing"
+
+  goldenbase "github.com/jimmc/golden/base"
+)
+
+func TestMain(m *testing.M) {
+  os.Exit(goldenbase.Main(m))
+}
+
+// disableUpdate turns off the -golden.update flag for the duration of
+// the test, for tests that expect a golden file mismatch.
+func disableUpdate(t *testing.T) {
+  update := *goldenbase.UpdateGolden
+  *goldenbase.UpdateGolden = false
+  t.Cleanup(func() { *goldenbase.UpdateGolden = update })
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+  "database/sql"
+  "fmt"
+  "io/ioutil"
+  "path"
+  "regexp"
+  "sort"
+  "strconv"
+
+  "github.com/jimmc/golden/base"
+)
+
+// MigrationsTable is the name of the table in which Migrate records
+// the versions of the migrations it has applied.
+var MigrationsTable = "schema_migrations"
+
+// migrationFileRe matches migration file names such as 0001_init.up.sql.
+var migrationFileRe = regexp.MustCompile(`^([0-9]+)_(.+)\.up\.sql$`)
+
+// Migration is one migration file.
+type Migration struct {
+  Version int64
+  Name string
+  Path string
+}
+
+// ReadMigrations returns the migrations in dir, sorted by version.
+// Migration files are named with a version number, an underscore, a name,
+// and the extension ".up.sql", such as 0001_init.up.sql.
+// Other files in dir are ignored.
+func ReadMigrations(dir string) ([]*Migration, error) {
+  base.RecordUsed(dir)
+  entries, err := ioutil.ReadDir(dir)
+  if err != nil {
+    return nil, err
+  }
+  migrations := make([]*Migration, 0)
+  for _, entry := range entries {
+    m := migrationFileRe.FindStringSubmatch(entry.Name())
+    if m == nil || entry.IsDir() {
+      continue
+    }
+    version, err := strconv.ParseInt(m[1], 10, 64)
+    if err != nil {
+      return nil, fmt.Errorf("bad version in migration file name %s: %v", entry.Name(), err)
+    }
+    migrations = append(migrations, &Migration{
+      Version: version,
+      Name: m[2],
+      Path: path.Join(dir, entry.Name()),
+    })
+  }
+  sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+  for i := 1; i < len(migrations); i++ {
+    if migrations[i].Version == migrations[i-1].Version {
+      return nil, fmt.Errorf("duplicate migration version %d in %s and %s",
+          migrations[i].Version, migrations[i-1].Path, migrations[i].Path)
+    }
+  }
+  return migrations, nil
+}
+
+// Migrate applies, in version order, each migration in dir that has not
+// already been applied, recording each one in MigrationsTable.
+// It returns the migrations that it applied.
+func Migrate(db *sql.DB, dir string) ([]*Migration, error) {
+  migrations, err := ReadMigrations(dir)
+  if err != nil {
+    return nil, err
+  }
+  applied, err := AppliedMigrations(db)
+  if err != nil {
+    return nil, err
+  }
+  newlyApplied := make([]*Migration, 0)
+  for _, m := range migrations {
+    if applied[m.Version] {
+      continue
+    }
+    if err := applyMigration(db, m); err != nil {
+      return newlyApplied, err
+    }
+    newlyApplied = append(newlyApplied, m)
+  }
+  return newlyApplied, nil
+}
+
+// AppliedMigrations returns the set of migration versions that have been
+// applied to the database, creating MigrationsTable if it does not exist.
+func AppliedMigrations(db *sql.DB) (map[int64]bool, error) {
+  table := QuoteIdentifier(MigrationsTable)
+  if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + table +
+      "(version integer primary key, name text not null)"); err != nil {
+    return nil, err
+  }
+  rows, err := db.Query("SELECT version FROM " + table)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  applied := make(map[int64]bool)
+  for rows.Next() {
+    var version int64
+    if err := rows.Scan(&version); err != nil {
+      return nil, err
+    }
+    applied[version] = true
+  }
+  return applied, rows.Err()
+}
+
+// applyMigration executes the migration file and records it in MigrationsTable
+// within a single transaction, so that a migration is never applied without
+// being recorded.
+func applyMigration(db *sql.DB, m *Migration) error {
+  migrationSql, err := ioutil.ReadFile(m.Path)
+  if err != nil {
+    return err
+  }
+  tx, err := db.Begin()
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+  if err := ExecMulti(tx, string(migrationSql)); err != nil {
+    return fmt.Errorf("error applying migration %s: %v", m.Path, err)
+  }
+  if _, err := tx.Exec("INSERT INTO " + QuoteIdentifier(MigrationsTable) +
+      "(version, name) VALUES(?, ?)", m.Version, m.Name); err != nil {
+    return fmt.Errorf("error recording migration %s: %v", m.Path, err)
+  }
+  if err := tx.Commit(); err != nil {
+    return fmt.Errorf("error committing migration %s: %v", m.Path, err)
+  }
+  return nil
+}
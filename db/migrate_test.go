@@ -0,0 +1,93 @@
+package db_test
+
+import (
+  "io/ioutil"
+  "path/filepath"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func TestReadMigrations(t *testing.T) {
+  migrations, err := db.ReadMigrations("testdata/migrations")
+  if err != nil {
+    t.Fatalf("ReadMigrations: %v", err)
+  }
+  if got, want := len(migrations), 2; got != want {
+    t.Fatalf("Wrong number of migrations, got %d, want %d", got, want)
+  }
+  if got, want := migrations[1].Version, int64(2); got != want {
+    t.Errorf("Migration version: got %d, want %d", got, want)
+  }
+  if got, want := migrations[1].Name, "label"; got != want {
+    t.Errorf("Migration name: got %q, want %q", got, want)
+  }
+}
+
+func TestMigrateTwice(t *testing.T) {
+  d, err := db.EmptyDb()
+  if err != nil {
+    t.Fatalf("EmptyDb: %v", err)
+  }
+  defer d.Close()
+  applied, err := db.Migrate(d, "testdata/migrations")
+  if err != nil {
+    t.Fatalf("Migrate: %v", err)
+  }
+  if got, want := len(applied), 2; got != want {
+    t.Errorf("First Migrate applied %d migrations, want %d", got, want)
+  }
+  applied, err = db.Migrate(d, "testdata/migrations")
+  if err != nil {
+    t.Fatalf("Migrate again: %v", err)
+  }
+  if got, want := len(applied), 0; got != want {
+    t.Errorf("Second Migrate applied %d migrations, want %d", got, want)
+  }
+}
+
+func TestMigrateTester(t *testing.T) {
+  r := db.NewTester("migrate", example)
+  r.MigrationsDir = "testdata/migrations"
+  r.DumpDb = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestMigrateNoDir(t *testing.T) {
+  r := db.NewTester("migrate", example)
+  r.MigrationsDir = "testdata/no-such-dir"
+  if err := r.Init(); err == nil {
+    t.Fatalf("Expected error for missing migrations directory")
+  }
+  r.Close()
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+  dir := t.TempDir()
+  migration := "CREATE TABLE partial(n int);\n\nINSERT INTO nosuchtable VALUES(1);\n"
+  if err := ioutil.WriteFile(filepath.Join(dir, "0001_partial.up.sql"), []byte(migration), 0644); err != nil {
+    t.Fatal(err)
+  }
+  d, err := db.EmptyDb()
+  if err != nil {
+    t.Fatalf("EmptyDb: %v", err)
+  }
+  defer d.Close()
+  d.SetMaxOpenConns(1)
+  if _, err := db.Migrate(d, dir); err == nil {
+    t.Fatalf("Expected error for failing migration")
+  }
+  applied, err := db.AppliedMigrations(d)
+  if err != nil {
+    t.Fatalf("AppliedMigrations: %v", err)
+  }
+  if len(applied) != 0 {
+    t.Errorf("Failed migration was recorded: %v", applied)
+  }
+  if _, err := d.Exec("SELECT * FROM partial"); err == nil {
+    t.Errorf("Failed migration was partially applied")
+  }
+}
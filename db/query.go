@@ -0,0 +1,122 @@
+package db
+
+import (
+  "context"
+  "database/sql"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+)
+
+// NewSqlTester creates a new instance of a Tester whose test function reads
+// the query file (see QueryFilePath) and runs each query in it against the
+// database prepared by the setup file, writing each query followed by its
+// results in QueryFormat to the output file.
+// Queries in the query file are separated by blank lines, and lines
+// starting with "#" are comments, as for setup files.
+func NewSqlTester(basename string) *Tester {
+  r := &Tester{}
+  r.BaseName = basename
+  r.TestFunc = r.runQueryFile
+  return r
+}
+
+// runQueryFile is the test function for NewSqlTester.
+func (r *Tester) runQueryFile(db *sql.DB, w io.Writer) error {
+  return RunQueryFileContext(r.Context(), db, w, r.QueryFormat, r.QueryFilePath())
+}
+
+// QueryFilePath returns the complete path to the query file.
+func (r *Tester) QueryFilePath() string {
+  return r.GetFilePath(r.QueryPath, r.QueryBaseName, "sql")
+}
+
+// RunQueryFile reads the queries from the given file and runs them with RunQueries.
+func RunQueryFile(db *sql.DB, w io.Writer, format Format, filename string) error {
+  return RunQueryFileContext(context.Background(), db, w, format, filename)
+}
+
+// RunQueryFileContext is like RunQueryFile, but runs the queries with ctx.
+func RunQueryFileContext(ctx context.Context, db *sql.DB, w io.Writer, format Format, filename string) error {
+  base.RecordUsed(filename)
+  queries, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return err
+  }
+  return RunQueriesContext(ctx, db, w, format, string(queries))
+}
+
+// RunQueries runs each query in the given string, writing to w the text of
+// the query, with each line prefixed by "> ", then the results of the query
+// in the given format, then a blank line.
+func RunQueries(db *sql.DB, w io.Writer, format Format, queries string) error {
+  return RunQueriesContext(context.Background(), db, w, format, queries)
+}
+
+// RunQueriesContext is like RunQueries, but runs the queries with ctx.
+func RunQueriesContext(ctx context.Context, db *sql.DB, w io.Writer, format Format, queries string) error {
+  for _, query := range QuerySegments(queries) {
+    for _, line := range strings.Split(query, "\n") {
+      if _, err := fmt.Fprintf(w, "> %s\n", line); err != nil {
+        return err
+      }
+    }
+    if err := WriteQueryContext(ctx, db, w, format, query); err != nil {
+      return fmt.Errorf("error running query %q: %v", query, err)
+    }
+    if _, err := io.WriteString(w, "\n"); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// QuerySegments splits a string of queries into segments with SplitSegments,
+// as for setup files, removes comment lines starting with "#" and surrounding whitespace
+// from each segment, and returns the segments that are not empty.
+func QuerySegments(queries string) []string {
+  segments := make([]string, 0)
+  for _, segment := range SplitSegments(queries) {
+    lines := make([]string, 0)
+    for _, line := range strings.Split(segment, "\n") {
+      if !strings.HasPrefix(line, "#") {
+        lines = append(lines, line)
+      }
+    }
+    segment = strings.TrimSpace(strings.Join(lines, "\n"))
+    if segment != "" {
+      segments = append(segments, segment)
+    }
+  }
+  return segments
+}
+
+// RunSqlTests runs a golden test with NewSqlTester for each query file
+// in dir, using the base name of the query file as the base name of the test,
+// so that each query file must have matching setup and golden files.
+// Each test is run as a subtest of t.
+func RunSqlTests(t *testing.T, dir string) {
+  t.Helper()
+  queryfiles, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+  if err != nil {
+    t.Fatalf("Error finding query files in %s: %v", dir, err)
+  }
+  if len(queryfiles) == 0 {
+    t.Fatalf("No query files in %s", dir)
+  }
+  for _, queryfile := range queryfiles {
+    basename := strings.TrimSuffix(filepath.Base(queryfile), ".sql")
+    t.Run(basename, func(t *testing.T) {
+      r := NewSqlTester(basename)
+      r.BaseDir = dir
+      if err := base.RunOne(r); err != nil {
+        t.Error(err)
+      }
+    })
+  }
+}
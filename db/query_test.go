@@ -0,0 +1,42 @@
+package db_test
+
+import (
+  "reflect"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func TestQuerySegments(t *testing.T) {
+  queries := "# comment\nSELECT 1;\n\n  \n\nSELECT 2\n  FROM x;\n# trailing comment\n"
+  got := db.QuerySegments(queries)
+  want := []string{"SELECT 1;", "SELECT 2\n  FROM x;"}
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("QuerySegments: got %q, want %q", got, want)
+  }
+}
+
+func TestSqlTests(t *testing.T) {
+  db.RunSqlTests(t, "testdata/queries")
+}
+
+func TestSqlTesterFormat(t *testing.T) {
+  r := db.NewSqlTester("books-csv")
+  r.BaseDir = "testdata/queries"
+  r.SetupBaseName = "books"
+  r.QueryBaseName = "books"
+  r.QueryFormat = db.FormatCSV
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestSqlTesterBadQuery(t *testing.T) {
+  r := db.NewSqlTester("bad-query")
+  r.SetupBaseName = "example"
+  r.QueryPath = "testdata/bad-setup.setup"
+  if err := base.RunOne(r); err == nil {
+    t.Fatalf("Expected error for bad query")
+  }
+}
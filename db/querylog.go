@@ -0,0 +1,257 @@
+package db
+
+import (
+  "context"
+  "database/sql"
+  "database/sql/driver"
+  "fmt"
+  "io"
+  "strings"
+  "sync"
+)
+
+// QueryLog records the SQL statements executed through a database
+// opened by OpenLoggedDb while recording is turned on.
+type QueryLog struct {
+  // If true, argument values are recorded as "?" rather than their values.
+  ScrubArgs bool
+
+  mu sync.Mutex
+  recording bool
+  entries []string
+}
+
+// NewQueryLog creates a new QueryLog with recording turned off.
+func NewQueryLog() *QueryLog {
+  return &QueryLog{}
+}
+
+// Start turns on recording.
+func (l *QueryLog) Start() {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  l.recording = true
+}
+
+// Stop turns off recording.
+func (l *QueryLog) Stop() {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  l.recording = false
+}
+
+// Reset discards all recorded entries.
+func (l *QueryLog) Reset() {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  l.entries = nil
+}
+
+// Entries returns a copy of the recorded entries.
+func (l *QueryLog) Entries() []string {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  return append([]string{}, l.entries...)
+}
+
+// Write writes the recorded entries to w, one per line.
+func (l *QueryLog) Write(w io.Writer) error {
+  for _, entry := range l.Entries() {
+    if _, err := fmt.Fprintln(w, entry); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// record adds an entry for the given kind of call, if recording is on.
+// The query has its whitespace collapsed, and each argument is written
+// as an SQL literal, or "?" if ScrubArgs is set.
+func (l *QueryLog) record(kind, query string, args []driver.NamedValue) {
+  l.mu.Lock()
+  defer l.mu.Unlock()
+  if !l.recording {
+    return
+  }
+  entry := kind + ": " + normalizeSQL(query)
+  if len(args) > 0 {
+    literals := make([]string, len(args))
+    for i, arg := range args {
+      if l.ScrubArgs {
+        literals[i] = "?"
+      } else {
+        literals[i] = sqlLiteral(arg.Value)
+      }
+      if arg.Name != "" {
+        literals[i] = arg.Name + "=" + literals[i]
+      }
+    }
+    entry += " [" + strings.Join(literals, ", ") + "]"
+  }
+  l.entries = append(l.entries, entry)
+}
+
+// OpenLoggedDb opens a database from the values in our variables
+// DbType and DbName, as does EmptyDb, but with a driver wrapper that
+// records in log each Exec and Query made while log is recording.
+func OpenLoggedDb(log *QueryLog) (*sql.DB, error) {
+  db, err := sql.Open(DbType, DbName)
+  if err != nil {
+    return nil, err
+  }
+  d := db.Driver()
+  db.Close()
+  return sql.OpenDB(&loggingConnector{driver: d, name: DbName, log: log}), nil
+}
+
+// loggingConnector opens connections that record to a QueryLog.
+type loggingConnector struct {
+  driver driver.Driver
+  name string
+  log *QueryLog
+}
+
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+  var conn driver.Conn
+  var err error
+  if dc, ok := c.driver.(driver.DriverContext); ok {
+    var connector driver.Connector
+    connector, err = dc.OpenConnector(c.name)
+    if err == nil {
+      conn, err = connector.Connect(ctx)
+    }
+  } else {
+    conn, err = c.driver.Open(c.name)
+  }
+  if err != nil {
+    return nil, err
+  }
+  return &loggingConn{conn: conn, log: c.log}, nil
+}
+
+func (c *loggingConnector) Driver() driver.Driver {
+  return c.driver
+}
+
+// loggingConn wraps a driver.Conn, recording Exec and Query calls.
+type loggingConn struct {
+  conn driver.Conn
+  log *QueryLog
+}
+
+// Unwrap returns the underlying driver connection.
+func (c *loggingConn) Unwrap() driver.Conn {
+  return c.conn
+}
+
+func (c *loggingConn) Prepare(query string) (driver.Stmt, error) {
+  return c.PrepareContext(context.Background(), query)
+}
+
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+  var stmt driver.Stmt
+  var err error
+  if pc, ok := c.conn.(driver.ConnPrepareContext); ok {
+    stmt, err = pc.PrepareContext(ctx, query)
+  } else {
+    stmt, err = c.conn.Prepare(query)
+  }
+  if err != nil {
+    return nil, err
+  }
+  return &loggingStmt{stmt: stmt, query: query, log: c.log}, nil
+}
+
+func (c *loggingConn) Close() error {
+  return c.conn.Close()
+}
+
+func (c *loggingConn) Begin() (driver.Tx, error) {
+  return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+  if bc, ok := c.conn.(driver.ConnBeginTx); ok {
+    return bc.BeginTx(ctx, opts)
+  }
+  return c.conn.Begin()
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+  ec, ok := c.conn.(driver.ExecerContext)
+  if !ok {
+    return nil, driver.ErrSkip
+  }
+  result, err := ec.ExecContext(ctx, query, args)
+  if err != driver.ErrSkip {
+    c.log.record("exec", query, args)
+  }
+  return result, err
+}
+
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+  qc, ok := c.conn.(driver.QueryerContext)
+  if !ok {
+    return nil, driver.ErrSkip
+  }
+  rows, err := qc.QueryContext(ctx, query, args)
+  if err != driver.ErrSkip {
+    c.log.record("query", query, args)
+  }
+  return rows, err
+}
+
+// loggingStmt wraps a driver.Stmt, recording Exec and Query calls.
+type loggingStmt struct {
+  stmt driver.Stmt
+  query string
+  log *QueryLog
+}
+
+func (s *loggingStmt) Close() error {
+  return s.stmt.Close()
+}
+
+func (s *loggingStmt) NumInput() int {
+  return s.stmt.NumInput()
+}
+
+func (s *loggingStmt) Exec(args []driver.Value) (driver.Result, error) {
+  return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *loggingStmt) Query(args []driver.Value) (driver.Rows, error) {
+  return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+  s.log.record("exec", s.query, args)
+  if sc, ok := s.stmt.(driver.StmtExecContext); ok {
+    return sc.ExecContext(ctx, args)
+  }
+  return s.stmt.Exec(values(args))
+}
+
+func (s *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+  s.log.record("query", s.query, args)
+  if sc, ok := s.stmt.(driver.StmtQueryContext); ok {
+    return sc.QueryContext(ctx, args)
+  }
+  return s.stmt.Query(values(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+  named := make([]driver.NamedValue, len(args))
+  for i, arg := range args {
+    named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+  }
+  return named
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+  vals := make([]driver.Value, len(args))
+  for i, arg := range args {
+    vals[i] = arg.Value
+  }
+  return vals
+}
@@ -0,0 +1,60 @@
+package db_test
+
+import (
+  "database/sql"
+  "io"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+// lookup is used as a function under test that runs queries with arguments.
+func lookup(d *sql.DB, w io.Writer) error {
+  if _, err := d.Exec("UPDATE test SET s = ? WHERE n = ?;", "b'", 2); err != nil {
+    return err
+  }
+  stmt, err := d.Prepare(`SELECT s, n
+      FROM test WHERE n = ?;`)
+  if err != nil {
+    return err
+  }
+  defer stmt.Close()
+  for _, n := range []int{1, 2} {
+    var s string
+    if err := stmt.QueryRow(n).Scan(&s, &n); err != nil {
+      return err
+    }
+  }
+  return example(d, w)
+}
+
+func TestQueryLog(t *testing.T) {
+  r := db.NewTester("querylog", lookup)
+  r.SetupBaseName = "example"
+  r.LogQueries = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestQueryLogToOut(t *testing.T) {
+  r := db.NewTester("querylog-scrubbed", lookup)
+  r.SetupBaseName = "example"
+  r.LogQueries = true
+  r.ScrubQueryArgs = true
+  r.QueryLogToOut = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestQueryLogWithSnapshot(t *testing.T) {
+  r := db.NewTester("querylog", lookup)
+  r.SetupBaseName = "example"
+  r.LogQueries = true
+  r.UseSnapshot = true
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+  "context"
+  "database/sql"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+)
+
+// Schema describes the tables, views, indexes and triggers in a database.
+type Schema struct {
+  Tables []*SchemaTable
+  Views []*SchemaView
+  Indexes []*SchemaIndex
+  Triggers []*SchemaTrigger
+}
+
+// SchemaTable describes one table and its columns, in column order.
+type SchemaTable struct {
+  Name string
+  Columns []*SchemaColumn
+}
+
+// SchemaColumn describes one column of a table.
+type SchemaColumn struct {
+  Name string
+  Type string
+  NotNull bool
+  // Default is the default value expression, or empty if there is none.
+  Default string
+  // PrimaryKey is the 1-based position of this column in the primary key,
+  // or 0 if it is not part of the primary key.
+  PrimaryKey int
+}
+
+// SchemaView describes one view.
+type SchemaView struct {
+  Name string
+  SQL string
+}
+
+// SchemaIndex describes one index.
+type SchemaIndex struct {
+  Name string
+  Table string
+  Unique bool
+  Columns []string
+}
+
+// SchemaTrigger describes one trigger.
+type SchemaTrigger struct {
+  Name string
+  Table string
+  SQL string
+}
+
+// Dialect reads the schema of a database using the introspection
+// facilities of a particular database type.
+type Dialect interface {
+  ReadSchema(ctx context.Context, db *sql.DB) (*Schema, error)
+}
+
+// Dialects maps a database driver name to the Dialect for that database.
+// Drivers not listed here use an InformationSchemaDialect for the "public" schema.
+var Dialects = map[string]Dialect{
+  "sqlite3": SQLiteDialect{},
+}
+
+// DialectFor returns the Dialect for the given database driver name.
+func DialectFor(driverName string) Dialect {
+  if d, ok := Dialects[driverName]; ok {
+    return d
+  }
+  return InformationSchemaDialect{Schema: "public"}
+}
+
+// ReadSchema reads the schema of the database using the Dialect for DbType.
+func ReadSchema(db *sql.DB) (*Schema, error) {
+  return ReadSchemaContext(context.Background(), db)
+}
+
+// ReadSchemaContext is like ReadSchema, but runs its queries with ctx.
+func ReadSchemaContext(ctx context.Context, db *sql.DB) (*Schema, error) {
+  schema, err := DialectFor(DbType).ReadSchema(ctx, db)
+  if err != nil {
+    return nil, err
+  }
+  schema.sort()
+  return schema, nil
+}
+
+// DumpSchema reads the schema of the database and writes it to w.
+func DumpSchema(db *sql.DB, w io.Writer) error {
+  return DumpSchemaContext(context.Background(), db, w)
+}
+
+// DumpSchemaContext is like DumpSchema, but runs its queries with ctx.
+func DumpSchemaContext(ctx context.Context, db *sql.DB, w io.Writer) error {
+  schema, err := ReadSchemaContext(ctx, db)
+  if err != nil {
+    return err
+  }
+  return schema.Write(w)
+}
+
+// NewSchemaTester creates a new instance of a Tester whose test function
+// writes the schema of the database, as set up by the setup file,
+// to the output file.
+func NewSchemaTester(basename string) *Tester {
+  return NewTesterContext(basename, DumpSchemaContext)
+}
+
+// sort sorts the tables, views, indexes and triggers by name.
+// Columns stay in table order.
+func (s *Schema) sort() {
+  sort.Slice(s.Tables, func(i, j int) bool { return s.Tables[i].Name < s.Tables[j].Name })
+  sort.Slice(s.Views, func(i, j int) bool { return s.Views[i].Name < s.Views[j].Name })
+  sort.Slice(s.Indexes, func(i, j int) bool { return s.Indexes[i].Name < s.Indexes[j].Name })
+  sort.Slice(s.Triggers, func(i, j int) bool { return s.Triggers[i].Name < s.Triggers[j].Name })
+}
+
+// Write writes the schema to w in a normalized text form, one line per item.
+// Types are upper-cased and whitespace in SQL text is collapsed.
+func (s *Schema) Write(w io.Writer) error {
+  ew := &errWriter{w: w}
+  for _, t := range s.Tables {
+    ew.printf("table %s\n", t.Name)
+    for _, c := range t.Columns {
+      ew.printf("  column %s %s", c.Name, normalizeType(c.Type))
+      if c.NotNull {
+        ew.printf(" NOT NULL")
+      }
+      if c.Default != "" {
+        ew.printf(" DEFAULT %s", c.Default)
+      }
+      if c.PrimaryKey > 0 {
+        ew.printf(" PRIMARY KEY %d", c.PrimaryKey)
+      }
+      ew.printf("\n")
+    }
+  }
+  for _, v := range s.Views {
+    ew.printf("view %s\n  %s\n", v.Name, normalizeSQL(v.SQL))
+  }
+  for _, x := range s.Indexes {
+    unique := ""
+    if x.Unique {
+      unique = " UNIQUE"
+    }
+    ew.printf("index %s on %s%s (%s)\n", x.Name, x.Table, unique, strings.Join(x.Columns, ", "))
+  }
+  for _, tr := range s.Triggers {
+    ew.printf("trigger %s on %s\n  %s\n", tr.Name, tr.Table, normalizeSQL(tr.SQL))
+  }
+  return ew.err
+}
+
+// TableNames returns the names of the tables in the schema.
+func (s *Schema) TableNames() []string {
+  names := make([]string, len(s.Tables))
+  for i, t := range s.Tables {
+    names[i] = t.Name
+  }
+  return names
+}
+
+// normalizeType returns a type name in upper case, or "(none)" if empty.
+func normalizeType(t string) string {
+  if t == "" {
+    return "(none)"
+  }
+  return strings.ToUpper(t)
+}
+
+// normalizeSQL collapses all runs of whitespace in s to single spaces.
+func normalizeSQL(s string) string {
+  return strings.Join(strings.Fields(s), " ")
+}
+
+// errWriter writes formatted output, remembering the first error.
+type errWriter struct {
+  w io.Writer
+  err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+  if ew.err == nil {
+    _, ew.err = fmt.Fprintf(ew.w, format, args...)
+  }
+}
+
+// SQLiteDialect reads the schema of an SQLite database from sqlite_master
+// and the table_info, index_list and index_info pragmas.
+type SQLiteDialect struct{}
+
+// ReadSchema reads the schema of an SQLite database.
+func (SQLiteDialect) ReadSchema(ctx context.Context, db *sql.DB) (*Schema, error) {
+  rows, err := db.QueryContext(ctx, "SELECT type, name, tbl_name, ifnull(sql, '') FROM sqlite_master ORDER BY name")
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  schema := &Schema{}
+  for rows.Next() {
+    var typ, name, table, sqltext string
+    if err := rows.Scan(&typ, &name, &table, &sqltext); err != nil {
+      return nil, err
+    }
+    switch typ {
+    case "table":
+      if !strings.HasPrefix(name, "sqlite_") {
+        schema.Tables = append(schema.Tables, &SchemaTable{Name: name})
+      }
+    case "view":
+      schema.Views = append(schema.Views, &SchemaView{Name: name, SQL: sqltext})
+    case "index":
+      schema.Indexes = append(schema.Indexes, &SchemaIndex{Name: name, Table: table})
+    case "trigger":
+      schema.Triggers = append(schema.Triggers, &SchemaTrigger{Name: name, Table: table, SQL: sqltext})
+    }
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  rows.Close()
+  for _, t := range schema.Tables {
+    if t.Columns, err = sqliteColumns(ctx, db, t.Name); err != nil {
+      return nil, err
+    }
+  }
+  for _, x := range schema.Indexes {
+    if err := sqliteIndex(ctx, db, x); err != nil {
+      return nil, err
+    }
+  }
+  return schema, nil
+}
+
+func sqliteColumns(ctx context.Context, db *sql.DB, table string) ([]*SchemaColumn, error) {
+  rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", ifnull(dflt_value, ''), pk
+      FROM pragma_table_info(?) ORDER BY cid`, table)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  columns := make([]*SchemaColumn, 0)
+  for rows.Next() {
+    c := &SchemaColumn{}
+    if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.Default, &c.PrimaryKey); err != nil {
+      return nil, err
+    }
+    columns = append(columns, c)
+  }
+  return columns, rows.Err()
+}
+
+func sqliteIndex(ctx context.Context, db *sql.DB, x *SchemaIndex) error {
+  if err := db.QueryRowContext(ctx, `SELECT "unique" FROM pragma_index_list(?) WHERE name = ?`,
+      x.Table, x.Name).Scan(&x.Unique); err != nil {
+    return err
+  }
+  rows, err := db.QueryContext(ctx, `SELECT ifnull(name, '(expression)') FROM pragma_index_info(?) ORDER BY seqno`, x.Name)
+  if err != nil {
+    return err
+  }
+  defer rows.Close()
+  for rows.Next() {
+    var column string
+    if err := rows.Scan(&column); err != nil {
+      return err
+    }
+    x.Columns = append(x.Columns, column)
+  }
+  return rows.Err()
+}
+
+// InformationSchemaDialect reads the schema of a database from the standard
+// information_schema views, for databases such as PostgreSQL and MySQL.
+// Indexes are not part of information_schema, so they are not included.
+type InformationSchemaDialect struct {
+  // The name of the schema to read, such as "public".
+  Schema string
+}
+
+// ReadSchema reads the schema of the database from information_schema.
+func (d InformationSchemaDialect) ReadSchema(ctx context.Context, db *sql.DB) (*Schema, error) {
+  schemaName := QuoteString(d.Schema)
+  schema := &Schema{}
+  tables := make(map[string]*SchemaTable)
+  rows, err := db.QueryContext(ctx, `SELECT table_name, table_type FROM information_schema.tables
+      WHERE table_schema = ` + schemaName + ` ORDER BY table_name`)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  for rows.Next() {
+    var name, typ string
+    if err := rows.Scan(&name, &typ); err != nil {
+      return nil, err
+    }
+    if typ == "VIEW" {
+      schema.Views = append(schema.Views, &SchemaView{Name: name})
+    } else {
+      t := &SchemaTable{Name: name}
+      tables[name] = t
+      schema.Tables = append(schema.Tables, t)
+    }
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  rows.Close()
+
+  rows, err = db.QueryContext(ctx, `SELECT table_name, column_name, data_type, is_nullable,
+      coalesce(column_default, '') FROM information_schema.columns
+      WHERE table_schema = ` + schemaName + ` ORDER BY table_name, ordinal_position`)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  for rows.Next() {
+    var table, nullable string
+    c := &SchemaColumn{}
+    if err := rows.Scan(&table, &c.Name, &c.Type, &nullable, &c.Default); err != nil {
+      return nil, err
+    }
+    c.NotNull = (nullable == "NO")
+    if t := tables[table]; t != nil {
+      t.Columns = append(t.Columns, c)
+    }
+  }
+  if err := rows.Err(); err != nil {
+    return nil, err
+  }
+  rows.Close()
+
+  rows, err = db.QueryContext(ctx, `SELECT DISTINCT trigger_name, event_object_table, action_statement
+      FROM information_schema.triggers
+      WHERE trigger_schema = ` + schemaName + ` ORDER BY trigger_name`)
+  if err != nil {
+    return nil, err
+  }
+  defer rows.Close()
+  for rows.Next() {
+    tr := &SchemaTrigger{}
+    if err := rows.Scan(&tr.Name, &tr.Table, &tr.SQL); err != nil {
+      return nil, err
+    }
+    schema.Triggers = append(schema.Triggers, tr)
+  }
+  return schema, rows.Err()
+}
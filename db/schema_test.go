@@ -0,0 +1,43 @@
+package db_test
+
+import (
+  "bytes"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func TestSchemaTester(t *testing.T) {
+  r := db.NewSchemaTester("schema")
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
+
+func TestSchemaWrite(t *testing.T) {
+  schema := &db.Schema{
+    Tables: []*db.SchemaTable{
+      {Name: "t", Columns: []*db.SchemaColumn{
+        {Name: "a", Type: "integer", NotNull: true, PrimaryKey: 1},
+        {Name: "b", Default: "0"},
+      }},
+    },
+    Triggers: []*db.SchemaTrigger{
+      {Name: "tr", Table: "t", SQL: "CREATE TRIGGER tr\n  AFTER INSERT ON t\n  BEGIN SELECT 1; END"},
+    },
+  }
+  var buf bytes.Buffer
+  if err := schema.Write(&buf); err != nil {
+    t.Fatalf("Write: %v", err)
+  }
+  want := `table t
+  column a INTEGER NOT NULL PRIMARY KEY 1
+  column b (none) DEFAULT 0
+trigger tr on t
+  CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END
+`
+  if got := buf.String(); got != want {
+    t.Errorf("Write: got %q, want %q", got, want)
+  }
+}
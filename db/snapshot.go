@@ -0,0 +1,98 @@
+package db
+
+import (
+  "context"
+  "database/sql"
+  "database/sql/driver"
+  "fmt"
+
+  sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Snapshot holds a copy of the contents of an SQLite database,
+// which can be restored into that database or another one.
+// It is created by TakeSnapshot.
+type Snapshot struct {
+  db *sql.DB
+}
+
+// TakeSnapshot copies the contents of db into a new in-memory database
+// using the SQLite backup API and returns it as a Snapshot.
+// If db is an in-memory database, it should be limited to a single
+// connection with SetMaxOpenConns(1), since each connection to an
+// in-memory database sees a separate database.
+func TakeSnapshot(db *sql.DB) (*Snapshot, error) {
+  snapdb, err := sql.Open("sqlite3", ":memory:")
+  if err != nil {
+    return nil, err
+  }
+  snapdb.SetMaxOpenConns(1)
+  if err := backupDb(snapdb, db); err != nil {
+    snapdb.Close()
+    return nil, fmt.Errorf("error taking database snapshot: %v", err)
+  }
+  return &Snapshot{db: snapdb}, nil
+}
+
+// Restore replaces the contents of db with the contents of the snapshot.
+func (s *Snapshot) Restore(db *sql.DB) error {
+  if err := backupDb(db, s.db); err != nil {
+    return fmt.Errorf("error restoring database snapshot: %v", err)
+  }
+  return nil
+}
+
+// Close releases the snapshot.
+func (s *Snapshot) Close() error {
+  return s.db.Close()
+}
+
+// backupDb copies the main database of src into dest.
+func backupDb(dest, src *sql.DB) error {
+  ctx := context.Background()
+  destConn, err := dest.Conn(ctx)
+  if err != nil {
+    return err
+  }
+  defer destConn.Close()
+  srcConn, err := src.Conn(ctx)
+  if err != nil {
+    return err
+  }
+  defer srcConn.Close()
+  return destConn.Raw(func(destDriverConn interface{}) error {
+    return srcConn.Raw(func(srcDriverConn interface{}) error {
+      destDriverConn = unwrapDriverConn(destDriverConn)
+      srcDriverConn = unwrapDriverConn(srcDriverConn)
+      destSqlite, ok := destDriverConn.(*sqlite3.SQLiteConn)
+      if !ok {
+        return fmt.Errorf("destination is not an sqlite3 connection: %T", destDriverConn)
+      }
+      srcSqlite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+      if !ok {
+        return fmt.Errorf("source is not an sqlite3 connection: %T", srcDriverConn)
+      }
+      backup, err := destSqlite.Backup("main", srcSqlite, "main")
+      if err != nil {
+        return err
+      }
+      if _, err := backup.Step(-1); err != nil {
+        backup.Finish()
+        return err
+      }
+      return backup.Finish()
+    })
+  })
+}
+
+// unwrapDriverConn returns the innermost driver connection of a connection
+// that wraps another, such as one opened by OpenLoggedDb.
+func unwrapDriverConn(conn interface{}) interface{} {
+  for {
+    wrapper, ok := conn.(interface{ Unwrap() driver.Conn })
+    if !ok {
+      return conn
+    }
+    conn = wrapper.Unwrap()
+  }
+}
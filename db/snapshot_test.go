@@ -0,0 +1,109 @@
+package db_test
+
+import (
+  "database/sql"
+  "fmt"
+  "strings"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func countRows(t *testing.T, d *sql.DB) int {
+  t.Helper()
+  var n int
+  if err := d.QueryRow("SELECT count(*) FROM test;").Scan(&n); err != nil {
+    t.Fatalf("Error counting rows: %v", err)
+  }
+  return n
+}
+
+func TestSnapshotRestore(t *testing.T) {
+  d, err := db.DbWithSetupFile("testdata/db1.txt")
+  if err != nil {
+    t.Fatalf("DbWithSetupFile: %v", err)
+  }
+  defer d.Close()
+  d.SetMaxOpenConns(1)
+  snapshot, err := db.TakeSnapshot(d)
+  if err != nil {
+    t.Fatalf("TakeSnapshot: %v", err)
+  }
+  defer snapshot.Close()
+  if _, err := d.Exec("DELETE FROM test WHERE n > 1;"); err != nil {
+    t.Fatalf("Error deleting rows: %v", err)
+  }
+  if got, want := countRows(t, d), 1; got != want {
+    t.Fatalf("Rows after delete: got %d, want %d", got, want)
+  }
+  if err := snapshot.Restore(d); err != nil {
+    t.Fatalf("Restore: %v", err)
+  }
+  if got, want := countRows(t, d), 3; got != want {
+    t.Errorf("Rows after restore: got %d, want %d", got, want)
+  }
+}
+
+func TestSnapshotTester(t *testing.T) {
+  r := db.NewTester("snapshot-1", modify)
+  r.BaseSetupPath = "testdata/snapshot-base.setup"
+  r.UseSnapshot = true
+  if err := r.Init(); err != nil {
+    t.Fatalf("Error in Init: %v", err)
+  }
+  defer r.Close()
+  if err := base.RunTest(r); err != nil {
+    t.Fatalf("Error in first test: %v", err)
+  }
+  r.BaseName = "snapshot-2"
+  r.TestFunc = example
+  if err := base.RunTest(r); err != nil {
+    t.Fatalf("Error in second test: %v", err)
+  }
+}
+
+// benchmarkSetup returns setup SQL that creates and fills several tables.
+func benchmarkSetup() string {
+  var sb strings.Builder
+  for i := 0; i < 20; i++ {
+    fmt.Fprintf(&sb, "CREATE table t%d(n int, s string);\n", i)
+    fmt.Fprintf(&sb, "CREATE index t%d_s on t%d(s);\n", i, i)
+    fmt.Fprintf(&sb, `INSERT into t%d(n, s)
+  WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 500)
+  SELECT x, 'row ' || x FROM c;`, i)
+    sb.WriteString("\n\n")
+  }
+  return sb.String()
+}
+
+func BenchmarkReplaySetup(b *testing.B) {
+  setup := benchmarkSetup()
+  for i := 0; i < b.N; i++ {
+    d, err := db.DbWithSetupString(setup)
+    if err != nil {
+      b.Fatalf("DbWithSetupString: %v", err)
+    }
+    d.Close()
+  }
+}
+
+func BenchmarkRestoreSnapshot(b *testing.B) {
+  d, err := db.DbWithSetupString(benchmarkSetup())
+  if err != nil {
+    b.Fatalf("DbWithSetupString: %v", err)
+  }
+  defer d.Close()
+  d.SetMaxOpenConns(1)
+  snapshot, err := db.TakeSnapshot(d)
+  if err != nil {
+    b.Fatalf("TakeSnapshot: %v", err)
+  }
+  defer snapshot.Close()
+  b.ResetTimer()
+  for i := 0; i < b.N; i++ {
+    if err := snapshot.Restore(d); err != nil {
+      b.Fatalf("Restore: %v", err)
+    }
+  }
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+  "bytes"
+  "database/sql"
+  "fmt"
+  "io/ioutil"
+  "path"
+  "strings"
+  "text/template"
+
+  "github.com/jimmc/golden/base"
+)
+
+// TemplateFuncs are the functions available to setup templates, in addition
+// to the standard text/template functions:
+//   seq N        returns the ints 1 through N.
+//   until N      returns the ints 0 through N-1.
+//   span A B     returns the ints A through B.
+//   add A B      returns A+B.
+//   sub A B      returns A-B.
+//   mul A B      returns A*B.
+//   quote S      returns S as a single-quoted SQL string literal.
+//   join SEP L   joins the list of strings L with the separator SEP.
+//   repeat N S   returns S repeated N times.
+var TemplateFuncs = template.FuncMap{
+  "seq": func(n int) []int {
+    return span(1, n)
+  },
+  "until": func(n int) []int {
+    return span(0, n - 1)
+  },
+  "span": span,
+  "add": func(a, b int) int {
+    return a + b
+  },
+  "sub": func(a, b int) int {
+    return a - b
+  },
+  "mul": func(a, b int) int {
+    return a * b
+  },
+  "quote": QuoteString,
+  "join": func(sep string, list []string) string {
+    return strings.Join(list, sep)
+  },
+  "repeat": func(n int, s string) string {
+    return strings.Repeat(s, n)
+  },
+}
+
+// span returns a slice of the ints from first through last, inclusive.
+func span(first, last int) []int {
+  if last < first {
+    return []int{}
+  }
+  list := make([]int, 0, last - first + 1)
+  for i := first; i <= last; i++ {
+    list = append(list, i)
+  }
+  return list
+}
+
+// QuoteString returns s as a single-quoted SQL string literal,
+// doubling any embedded single quotes.
+func QuoteString(s interface{}) string {
+  return "'" + strings.ReplaceAll(fmt.Sprint(s), "'", "''") + "'"
+}
+
+// ExpandSetupTemplate runs setupSql through text/template with the given
+// params as the data value and TemplateFuncs available, and returns the result.
+// The name is used in error messages.
+func ExpandSetupTemplate(name, setupSql string, params interface{}) (string, error) {
+  tmpl, err := template.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(setupSql)
+  if err != nil {
+    return "", fmt.Errorf("error parsing setup template %s: %v", name, err)
+  }
+  var buf bytes.Buffer
+  if err := tmpl.Execute(&buf, params); err != nil {
+    return "", fmt.Errorf("error executing setup template %s: %v", name, err)
+  }
+  return buf.String(), nil
+}
+
+// LoadSetupTemplateFile reads the specified file, expands it as a template
+// using ExpandSetupTemplate, and executes the resulting SQL commands.
+func LoadSetupTemplateFile(db *sql.DB, filename string, params interface{}) error {
+  base.RecordUsed(filename)
+  setupSql, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return err
+  }
+  return LoadSetupTemplateString(db, path.Base(filename), string(setupSql), params)
+}
+
+// LoadSetupTemplateString expands the given string as a template
+// using ExpandSetupTemplate, and executes the resulting SQL commands.
+func LoadSetupTemplateString(db *sql.DB, name, setupSql string, params interface{}) error {
+  expanded, err := ExpandSetupTemplate(name, setupSql, params)
+  if err != nil {
+    return err
+  }
+  return LoadSetupString(db, expanded)
+}
@@ -0,0 +1,46 @@
+package db_test
+
+import (
+  "reflect"
+  "testing"
+
+  "github.com/jimmc/golden/base"
+  "github.com/jimmc/golden/db"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+  tmpl := `{{range seq 3}}{{.}} {{end}}|{{range until 2}}{{.}} {{end}}|{{range span 4 5}}{{.}} {{end}}|{{quote .}}`
+  got, err := db.ExpandSetupTemplate("funcs", tmpl, "a'b")
+  if err != nil {
+    t.Fatalf("ExpandSetupTemplate: %v", err)
+  }
+  if want := "1 2 3 |0 1 |4 5 |'a''b'"; got != want {
+    t.Errorf("ExpandSetupTemplate: got %q, want %q", got, want)
+  }
+}
+
+func TestTemplateMissingParam(t *testing.T) {
+  _, err := db.ExpandSetupTemplate("missing", "{{.NoSuchKey}}", map[string]interface{}{})
+  if err == nil {
+    t.Fatalf("Expected error for missing template parameter")
+  }
+}
+
+func TestQuoteString(t *testing.T) {
+  got := []string{db.QuoteString("abc"), db.QuoteString("it's"), db.QuoteString(12)}
+  want := []string{"'abc'", "'it''s'", "'12'"}
+  if !reflect.DeepEqual(got, want) {
+    t.Errorf("QuoteString: got %v, want %v", got, want)
+  }
+}
+
+func TestTemplateSetup(t *testing.T) {
+  r := db.NewTester("template", example)
+  r.SetupParams = map[string]interface{}{
+    "Count": 3,
+    "Prefix": "it's",
+  }
+  if err := base.RunOne(r); err != nil {
+    t.Fatalf("Error in RunOne: %v", err)
+  }
+}
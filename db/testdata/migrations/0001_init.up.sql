@@ -0,0 +1,2 @@
+# Initial schema.
+CREATE table test(n int, s string);
@@ -0,0 +1,3 @@
+ALTER table test ADD COLUMN label text default 'none';
+
+CREATE index idx_test_s ON test(s);
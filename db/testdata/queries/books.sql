@@ -0,0 +1,9 @@
+# Books with their authors.
+SELECT a.name, b.title, b.year
+  FROM book b JOIN author a ON a.id = b.author_id
+  ORDER BY b.year;
+
+# Books per author.
+SELECT a.name, count(*) AS books
+  FROM book b JOIN author a ON a.id = b.author_id
+  GROUP BY a.name ORDER BY a.name;
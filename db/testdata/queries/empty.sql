@@ -0,0 +1 @@
+SELECT n, s FROM test;
@@ -1,22 +1,83 @@
 package db
 
 import (
+  "bufio"
+  "context"
   "database/sql"
+  "fmt"
   "io"
+  "os"
+  "path"
+  "strings"
 
   "github.com/jimmc/golden/base"
 )
 
 // Tester provides the structure for running unit tests with database setup files.
+// The test function, TestFunc, receives the database as its fixture,
+// which Init opens and sets in both Fixture and DB.
 type Tester struct {
-  base.Tester
+  base.FixtureTester[*sql.DB]
 
   // Base name for the test setup file; if not set, uses BaseName.
   SetupBaseName string
   // Path to the test setup file; if not set, uses SetupBaseName.
   SetupPath string
+  // Base name for the query file used by NewSqlTester; if not set, uses BaseName.
+  QueryBaseName string
+  // Path to the query file used by NewSqlTester; if not set, uses QueryBaseName.
+  QueryPath string
+  // The format in which NewSqlTester writes query results.
+  QueryFormat Format
 
+  // Directory of migration files to apply in Init; if not set, no migrations are applied.
+  // See Migrate.
+  MigrationsDir string
+  // Path to a setup file that is loaded once in Init, after migrations;
+  // if not set, there is no base setup file.
+  BaseSetupPath string
+  // If true, Init takes a snapshot of the database after migrations and the
+  // base setup file, and Arrange restores that snapshot before loading the
+  // setup file, so that each test starts from the same database state
+  // without replaying SQL. This limits DB to a single connection.
+  UseSnapshot bool
+  // Parameters for the setup file; if not nil, the setup file is expanded
+  // as a text/template with these parameters before it is executed.
+  SetupParams interface{}
+  // If true, Arrange loads the fixture files that go with the setup file
+  // after loading the setup file. See LoadFixtures.
+  LoadFixtures bool
+
+  // If true, Assert also dumps the database after the test and compares
+  // that dump to a second golden file.
+  DumpDb bool
+  // Tables to dump when DumpDb is set; if empty, all tables are dumped.
+  DumpTableNames []string
+  // Path to the database dump output file; if not set, uses OutBaseName.
+  DbOutPath string
+  // Path to the database dump golden file; if not set, uses GoldenBaseName.
+  DbGoldenPath string
+
+  // If true, Init opens DB with a wrapper that records the SQL statements
+  // executed during Act in QueryLog, and Assert compares that log to
+  // a query log golden file.
+  LogQueries bool
+  // If true, argument values are scrubbed from the query log.
+  ScrubQueryArgs bool
+  // If true, the query log is written to the end of the output file
+  // rather than to a separate file.
+  QueryLogToOut bool
+  // Path to the query log output file; if not set, uses OutBaseName.
+  SqlOutPath string
+  // Path to the query log golden file; if not set, uses GoldenBaseName.
+  SqlGoldenPath string
+
+  // The database; the same as Fixture.
   DB *sql.DB
+  // The log of SQL statements executed by the test when LogQueries is set.
+  QueryLog *QueryLog
+
+  snapshot *Snapshot
 }
 
 // NewTester creates a new instance of a Tester that will call the specified
@@ -24,42 +85,231 @@ type Tester struct {
 func NewTester(basename string, callback func(*sql.DB, io.Writer) error) *Tester {
   r := &Tester{}
   r.BaseName = basename
-  r.Test = func(baseR *base.Tester) error {
-    return callback(r.DB, r.OutW)
+  r.TestFunc = callback
+  return r
+}
+
+// NewTesterContext is like NewTester, but the callback also receives the
+// context of the test, which is cancelled when the test times out.
+func NewTesterContext(basename string, callback func(context.Context, *sql.DB, io.Writer) error) *Tester {
+  r := &Tester{}
+  r.BaseName = basename
+  r.TestFunc = func(db *sql.DB, w io.Writer) error {
+    return callback(r.Context(), db, w)
   }
   return r
 }
 
+// NewQueryTester creates a new instance of a Tester whose test function
+// runs the given query and writes the results in the given format.
+func NewQueryTester(basename string, format Format, query string, args ...interface{}) *Tester {
+  return NewTesterContext(basename, func(ctx context.Context, db *sql.DB, w io.Writer) error {
+    return WriteQueryContext(ctx, db, w, format, query, args...)
+  })
+}
+
+// WriteQuery runs the query and writes the results in the given format
+// to the output file.
+func (r *Tester) WriteQuery(format Format, query string, args ...interface{}) error {
+  return WriteQueryContext(r.Context(), r.DB, r.OutW, format, query, args...)
+}
+
 // SetupFilePath returns the complete path to the setup file.
 func (r *Tester) SetupFilePath() string {
   return r.GetFilePath(r.SetupPath, r.SetupBaseName, "setup")
 }
 
-// Init initializes our database.
+// DbOutFilePath returns the complete path to the database dump output file.
+func (r *Tester) DbOutFilePath() string {
+  return r.GetFilePath(r.DbOutPath, r.OutBaseName, "dbout")
+}
+
+// DbGoldenFilePath returns the complete path to the database dump golden file.
+func (r *Tester) DbGoldenFilePath() string {
+  return r.GetFilePath(r.DbGoldenPath, r.GoldenBaseName, "dbgolden")
+}
+
+// SqlOutFilePath returns the complete path to the query log output file.
+func (r *Tester) SqlOutFilePath() string {
+  return r.GetFilePath(r.SqlOutPath, r.OutBaseName, "sqlout")
+}
+
+// SqlGoldenFilePath returns the complete path to the query log golden file.
+func (r *Tester) SqlGoldenFilePath() string {
+  return r.GetFilePath(r.SqlGoldenPath, r.GoldenBaseName, "sqlgolden")
+}
+
+// Init initializes our database, applies migrations from MigrationsDir,
+// loads the BaseSetupPath file, and takes a snapshot if UseSnapshot is set.
 func (r *Tester) Init() error {
-  db, err := EmptyDb()
+  var db *sql.DB
+  var err error
+  if r.LogQueries {
+    r.QueryLog = NewQueryLog()
+    r.QueryLog.ScrubArgs = r.ScrubQueryArgs
+    db, err = OpenLoggedDb(r.QueryLog)
+  } else {
+    db, err = EmptyDb()
+  }
   if err != nil {
     return err
   }
   r.DB = db
+  r.Fixture = db
+  if r.UseSnapshot {
+    db.SetMaxOpenConns(1)
+  }
+  if r.MigrationsDir != "" {
+    if _, err := Migrate(db, r.MigrationsDir); err != nil {
+      return err
+    }
+  }
+  if r.BaseSetupPath != "" {
+    if err := LoadSetupFile(db, r.BaseSetupPath); err != nil {
+      return err
+    }
+  }
+  if r.UseSnapshot {
+    snapshot, err := TakeSnapshot(db)
+    if err != nil {
+      return err
+    }
+    r.snapshot = snapshot
+  }
   return nil
 }
 
-// Arrange prepares the output file and loads the setup file.
+// Arrange prepares the output file, restores the snapshot taken by Init
+// if UseSnapshot is set, loads the setup file, then, if LoadFixtures is set,
+// loads any fixture files that go with the setup file.
 func (r *Tester) Arrange() error {
   if err := r.Tester.Arrange(); err != nil {
     return err
   }
-  if err := LoadSetupFile(r.DB, r.SetupFilePath()); err != nil {
-    return err
+  if r.snapshot != nil {
+    if err := r.snapshot.Restore(r.DB); err != nil {
+      return err
+    }
+  }
+  setupfilepath := r.SetupFilePath()
+  if r.SetupParams != nil {
+    if err := LoadSetupTemplateFile(r.DB, setupfilepath, r.SetupParams); err != nil {
+      return err
+    }
+  } else {
+    if err := LoadSetupFile(r.DB, setupfilepath); err != nil {
+      return err
+    }
+  }
+  if r.LoadFixtures {
+    fixturebasename := strings.TrimSuffix(path.Base(setupfilepath), path.Ext(setupfilepath))
+    if err := LoadFixtures(r.DB, path.Dir(setupfilepath), fixturebasename); err != nil {
+      return err
+    }
   }
   return nil
 }
 
-// Close closes the database
+// Act calls the test function with the database, recording its SQL statements if LogQueries is set.
+func (r *Tester) Act() error {
+  return r.ActWithQueryLog(r.FixtureTester.Act)
+}
+
+// ActWithQueryLog calls act. If LogQueries is set, the query log is cleared
+// and recording is turned on while act runs. If QueryLogToOut is also set,
+// the query log is then written to the output file.
+func (r *Tester) ActWithQueryLog(act func() error) error {
+  if r.QueryLog == nil {
+    return act()
+  }
+  r.QueryLog.Reset()
+  r.QueryLog.Start()
+  err := act()
+  r.QueryLog.Stop()
+  if err != nil || !r.QueryLogToOut {
+    return err
+  }
+  return r.QueryLog.Write(r.OutW)
+}
+
+// Assert checks the output against the golden file. If DumpDb is set,
+// it also dumps the database and checks that against the database golden file.
+// If LogQueries is set and QueryLogToOut is not, it also checks the query log
+// against the query log golden file. If more than one check fails,
+// it returns a MultiError listing all of the failures.
+func (r *Tester) Assert() error {
+  errs := make(base.MultiError, 0)
+  if err := r.Tester.Assert(); err != nil {
+    errs = append(errs, err)
+  }
+  if r.DumpDb {
+    if err := r.AssertDbDump(); err != nil {
+      errs = append(errs, err)
+    }
+  }
+  if r.QueryLog != nil && !r.QueryLogToOut {
+    if err := r.AssertQueryLog(); err != nil {
+      errs = append(errs, err)
+    }
+  }
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
+  }
+  return errs
+}
+
+// AssertQueryLog writes the query log to the query log output file, and
+// compares that file to the query log golden file.
+func (r *Tester) AssertQueryLog() error {
+  outfilepath := r.SqlOutFilePath()
+  os.Remove(outfilepath)
+  f, err := os.Create(outfilepath)
+  if err != nil {
+    return fmt.Errorf("error creating query log file %q: %v", outfilepath, err)
+  }
+  w := bufio.NewWriter(f)
+  err = r.QueryLog.Write(w)
+  w.Flush()
+  f.Close()
+  if err != nil {
+    return err
+  }
+  return r.CompareFile(outfilepath, r.SqlGoldenFilePath(), nil)
+}
+
+// AssertDbDump dumps the tables listed in DumpTableNames, or all tables if
+// that is empty, to the database dump output file, and compares that file
+// to the database dump golden file.
+func (r *Tester) AssertDbDump() error {
+  outfilepath := r.DbOutFilePath()
+  os.Remove(outfilepath)
+  f, err := os.Create(outfilepath)
+  if err != nil {
+    return fmt.Errorf("error creating database dump file %q: %v", outfilepath, err)
+  }
+  w := bufio.NewWriter(f)
+  err = DumpTables(r.DB, w, r.DumpTableNames...)
+  w.Flush()
+  f.Close()
+  if err != nil {
+    return err
+  }
+  return r.CompareFile(outfilepath, r.DbGoldenFilePath(), nil)
+}
+
+// Close closes the database and releases the snapshot.
 func (r *Tester) Close() error {
+  if r.snapshot != nil {
+    r.snapshot.Close()
+    r.snapshot = nil
+  }
   if r.DB != nil {
     r.DB.Close()
+    r.DB = nil
+    r.Fixture = nil
   }
   return nil
 }
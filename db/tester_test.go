@@ -1,7 +1,9 @@
 package db_test
 
 import (
+  "context"
   "database/sql"
+  "errors"
   "fmt"
   "io"
   "testing"
@@ -88,6 +90,7 @@ func TestSetupError(t *testing.T) {
 // TestGoldenMismatch tests the case where the output does not match
 // what we expect to see.
 func TestGoldenMismatch(t *testing.T) {
+  disableUpdate(t)
   r := db.NewTester("example-no-match", example)
   r.SetupBaseName = "example"
   if err := r.Init(); err != nil {
@@ -106,3 +109,23 @@ func TestGoldenMismatch(t *testing.T) {
     t.Fatalf("Error in Close: %v", err)
   }
 }
+
+// TestQueryTesterCancelled tests that the query of a NewQueryTester
+// runs with the context of the test.
+func TestQueryTesterCancelled(t *testing.T) {
+  r := db.NewQueryTester("example-query", db.FormatTable, "SELECT s, n FROM test ORDER BY s")
+  r.SetupBaseName = "example"
+  if err := r.Init(); err != nil {
+    t.Fatalf("Error in Init: %v", err)
+  }
+  defer r.Close()
+  if err := r.Arrange(); err != nil {
+    t.Fatalf("Error in Arrange: %v", err)
+  }
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  r.SetContext(ctx)
+  if err := r.Act(); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Act with cancelled context: got %v, want %v", err, context.Canceled)
+  }
+}
@@ -5,4 +5,5 @@ go 1.18
 require (
 	github.com/google/go-cmp v0.5.8
 	github.com/mattn/go-sqlite3 v1.14.15
+	gopkg.in/yaml.v3 v3.0.1
 )
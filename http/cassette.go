@@ -0,0 +1,329 @@
+package http
+
+import (
+  "bytes"
+  "encoding/json"
+  "flag"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync"
+
+  goldenbase "github.com/jimmc/golden/base"
+)
+
+// RecordCassettes is set by the -golden.record flag. When true, cassettes
+// record the interactions with their targets instead of replaying them.
+var RecordCassettes = flag.Bool("golden.record", false, "record HTTP cassettes instead of replaying them")
+
+// CassetteRequest is a request as recorded in a cassette.
+type CassetteRequest struct {
+  Method string `json:"method"`
+  URL string `json:"url"`
+  Header http.Header `json:"header,omitempty"`
+  Body string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a response as recorded in a cassette.
+type CassetteResponse struct {
+  Status int `json:"status"`
+  Header http.Header `json:"header,omitempty"`
+  Body string `json:"body,omitempty"`
+}
+
+// Interaction is one request and its response.
+type Interaction struct {
+  Request CassetteRequest `json:"request"`
+  Response CassetteResponse `json:"response"`
+}
+
+// Cassette is an http.RoundTripper that records HTTP interactions to a
+// cassette file or replays them from that file.
+// Each test is started with Start, which names the cassette file, and is
+// ended with Finish. When recording, the Cassette sends each request to
+// Target and records the request and response. When replaying, it answers
+// each request with the next recorded response, after checking that the
+// request has the same method, URL and body as the recorded request.
+// Bodies are recorded as text.
+// The same Cassette can be used for a sequence of tests, so that the code
+// under test can be given its client once.
+type Cassette struct {
+  // The RoundTripper to send requests to when recording, such as a
+  // transport to a local stand-in server, or HandlerTransport for a
+  // handler under our control. Recording fails if this is not set,
+  // so that recording never sends requests to real services by accident.
+  Target http.RoundTripper
+  // Request headers that are not recorded.
+  IgnoreHeaders []string
+  // Response headers that are not recorded.
+  IgnoreResponseHeaders []string
+
+  mu sync.Mutex
+  started bool
+  path string
+  recording bool
+  interactions []*Interaction
+  next int
+  errs goldenbase.MultiError
+}
+
+// NewCassette creates a Cassette that records by sending requests to target.
+// By default the User-Agent, Accept-Encoding and Content-Length headers,
+// which are added by the HTTP transport, and the Authorization and Cookie
+// headers, which may hold credentials, are not recorded from requests,
+// and the Date, Content-Length and Set-Cookie headers are not recorded
+// from responses.
+func NewCassette(target http.RoundTripper) *Cassette {
+  return &Cassette{
+    Target: target,
+    IgnoreHeaders: []string{"User-Agent", "Accept-Encoding", "Content-Length", "Authorization", "Cookie"},
+    IgnoreResponseHeaders: []string{"Date", "Content-Length", "Set-Cookie"},
+  }
+}
+
+// Start starts a test using the cassette file at path. If record is true,
+// the Cassette records to that file when the test is finished,
+// otherwise it reads the file to replay it.
+func (c *Cassette) Start(path string, record bool) error {
+  goldenbase.RecordUsed(path)
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.started = false
+  c.path = path
+  c.recording = record
+  c.interactions = nil
+  c.next = 0
+  c.errs = nil
+  if record {
+    if c.Target == nil {
+      return fmt.Errorf("cassette %s: can not record without a Target", path)
+    }
+    c.started = true
+    return nil
+  }
+  data, err := ioutil.ReadFile(path)
+  if err != nil {
+    return fmt.Errorf("error reading cassette (use -golden.record to record it): %v", err)
+  }
+  if err := json.Unmarshal(data, &c.interactions); err != nil {
+    return fmt.Errorf("error parsing cassette %s: %v", path, err)
+  }
+  c.started = true
+  return nil
+}
+
+// Recording returns true if the Cassette is recording rather than replaying.
+func (c *Cassette) Recording() bool {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return c.recording
+}
+
+// Interactions returns the interactions recorded or being replayed
+// in the current test.
+func (c *Cassette) Interactions() []*Interaction {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  return append([]*Interaction{}, c.interactions...)
+}
+
+// Client returns an http.Client that sends its requests to the Cassette.
+func (c *Cassette) Client() *http.Client {
+  return &http.Client{Transport: c}
+}
+
+// RoundTrip records or replays one interaction.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+  creq, err := c.cassetteRequest(req)
+  if err != nil {
+    return nil, err
+  }
+  c.mu.Lock()
+  started, recording := c.started, c.recording
+  c.mu.Unlock()
+  if !started {
+    return nil, fmt.Errorf("cassette request %s %s made outside of a test", creq.Method, creq.URL)
+  }
+  if recording {
+    return c.record(req, creq)
+  }
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  n := c.next
+  if n >= len(c.interactions) {
+    err := fmt.Errorf("cassette %s: unexpected request %d %s %s", c.path, n + 1, creq.Method, creq.URL)
+    c.errs = append(c.errs, err)
+    return nil, err
+  }
+  c.next++
+  recorded := c.interactions[n]
+  if problems := compareRequests(&recorded.Request, creq); problems != "" {
+    err := fmt.Errorf("cassette %s: request %d does not match the recorded request:\n%s", c.path, n + 1, problems)
+    c.errs = append(c.errs, err)
+    return nil, err
+  }
+  resp := &http.Response{
+    StatusCode: recorded.Response.Status,
+    Status: fmt.Sprintf("%d %s", recorded.Response.Status, http.StatusText(recorded.Response.Status)),
+    Proto: "HTTP/1.1",
+    ProtoMajor: 1,
+    ProtoMinor: 1,
+    Header: recorded.Response.Header.Clone(),
+    Body: ioutil.NopCloser(strings.NewReader(recorded.Response.Body)),
+    ContentLength: int64(len(recorded.Response.Body)),
+    Request: req,
+  }
+  if resp.Header == nil {
+    resp.Header = make(http.Header)
+  }
+  return resp, nil
+}
+
+// record sends the request to the Target and records the interaction.
+func (c *Cassette) record(req *http.Request, creq *CassetteRequest) (*http.Response, error) {
+  resp, err := c.Target.RoundTrip(req)
+  if err != nil {
+    return nil, err
+  }
+  body, err := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if err != nil {
+    return nil, err
+  }
+  resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+  header := resp.Header.Clone()
+  for _, h := range c.IgnoreResponseHeaders {
+    header.Del(h)
+  }
+  if len(header) == 0 {
+    header = nil
+  }
+
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.interactions = append(c.interactions, &Interaction{
+    Request: *creq,
+    Response: CassetteResponse{Status: resp.StatusCode, Header: header, Body: string(body)},
+  })
+  return resp, nil
+}
+
+// cassetteRequest converts the request to a CassetteRequest, reading the
+// body and replacing it so that it can still be sent.
+func (c *Cassette) cassetteRequest(req *http.Request) (*CassetteRequest, error) {
+  creq := &CassetteRequest{
+    Method: req.Method,
+    URL: req.URL.String(),
+  }
+  if req.Body != nil {
+    body, err := ioutil.ReadAll(req.Body)
+    req.Body.Close()
+    if err != nil {
+      return nil, err
+    }
+    req.Body = ioutil.NopCloser(bytes.NewReader(body))
+    creq.Body = string(body)
+  }
+  for key, values := range req.Header {
+    if !c.ignoreHeader(key) {
+      if creq.Header == nil {
+        creq.Header = make(http.Header)
+      }
+      creq.Header[key] = append([]string{}, values...)
+    }
+  }
+  return creq, nil
+}
+
+// ignoreHeader returns true if the header is in IgnoreHeaders.
+func (c *Cassette) ignoreHeader(key string) bool {
+  for _, h := range c.IgnoreHeaders {
+    if http.CanonicalHeaderKey(h) == key {
+      return true
+    }
+  }
+  return false
+}
+
+// compareRequests returns a description of the differences in method,
+// URL and body between the recorded and actual requests, or the empty
+// string if there are none.
+func compareRequests(recorded, actual *CassetteRequest) string {
+  problems := make([]string, 0)
+  if recorded.Method != actual.Method {
+    problems = append(problems, fmt.Sprintf("method: recorded %s, got %s", recorded.Method, actual.Method))
+  }
+  if recorded.URL != actual.URL {
+    problems = append(problems, fmt.Sprintf("url: recorded %s, got %s", recorded.URL, actual.URL))
+  }
+  if recorded.Body != actual.Body {
+    problems = append(problems, "body (-recorded +got):\n" + goldenbase.TextDiff(recorded.Body, actual.Body))
+  }
+  return strings.Join(problems, "\n")
+}
+
+// Finish ends the test started by Start. If recording, it writes the
+// cassette file. If replaying, it returns an error if any request did not
+// match or any recorded interaction was not used.
+// Calling Finish when no test is started does nothing.
+func (c *Cassette) Finish() error {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  if !c.started {
+    return nil
+  }
+  c.started = false
+  if c.recording {
+    data, err := json.MarshalIndent(c.interactions, "", "  ")
+    if err != nil {
+      return err
+    }
+    if err := ioutil.WriteFile(c.path, append(data, '\n'), 0644); err != nil {
+      return fmt.Errorf("error writing cassette %s: %v", c.path, err)
+    }
+    return nil
+  }
+  errs := c.errs
+  if c.next < len(c.interactions) && len(errs) == 0 {
+    errs = append(errs, fmt.Errorf("cassette %s: %d of %d recorded requests were not made",
+        c.path, len(c.interactions) - c.next, len(c.interactions)))
+  }
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
+  }
+  return errs
+}
+
+// HandlerTransport returns an http.RoundTripper that sends each request
+// directly to the handler, for use as the Target of a Cassette.
+func HandlerTransport(handler http.Handler) http.RoundTripper {
+  return handlerTransport{handler}
+}
+
+type handlerTransport struct {
+  handler http.Handler
+}
+
+// RoundTrip calls the handler with the request and returns its response.
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  rr := httptest.NewRecorder()
+  t.handler.ServeHTTP(rr, req)
+  return rr.Result(), nil
+}
+
+// discard ends the test started by Start without writing or checking
+// the cassette file, for a test that did not get as far as Assert.
+func (c *Cassette) discard() {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+  c.started = false
+  c.interactions = nil
+  c.next = 0
+  c.errs = nil
+}
@@ -0,0 +1,12 @@
+package http_test
+
+import (
+  "os"
+  "testing"
+
+  goldenbase "github.com/jimmc/golden/base"
+)
+
+func TestMain(m *testing.M) {
+  os.Exit(goldenbase.Main(m))
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+  goldenbase "github.com/jimmc/golden/base"
+)
+
+// Outbound holds the fake services that a handler under test sends its
+// outbound requests to. It is embedded in the HTTP testers, which call
+// StartOutbound from Arrange, FinishOutbound from Assert, and CloseOutbound
+// from Close.
+type Outbound struct {
+  // A fake upstream service. If set, each test answers the requests sent
+  // to it with the canned responses in <basename>.upstream-N.response and
+  // records them in the "upstream" output, which Assert compares to
+  // <basename>.upstream.golden.
+  Upstream *Upstream
+  // A cassette. If set, each test replays the interactions recorded in
+  // <basename>.cassette, or, with the -golden.record flag, records them
+  // by sending the requests to the cassette's Target.
+  Cassette *Cassette
+}
+
+// StartOutbound starts a test on the Upstream and the Cassette, if they
+// are set, using the file paths and outputs of r. It first abandons any
+// earlier test that did not reach FinishOutbound.
+func (o *Outbound) StartOutbound(r *goldenbase.Tester) error {
+  o.abandonOutbound()
+  if o.Upstream != nil {
+    o.Upstream.Start(r.GetFilePath("", "", "upstream"), r.Output("upstream"))
+  }
+  if o.Cassette != nil {
+    if err := o.Cassette.Start(r.GetFilePath("", "", "cassette"), *RecordCassettes); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// FinishOutbound finishes the test on the Upstream and the Cassette, if
+// they are set. It returns an error if there were canned upstream
+// responses that were not used or cassette requests that did not match.
+func (o *Outbound) FinishOutbound() error {
+  errs := make(goldenbase.MultiError, 0)
+  if o.Upstream != nil {
+    if err := o.Upstream.Finish(); err != nil {
+      errs = append(errs, err)
+    }
+  }
+  if o.Cassette != nil {
+    if err := o.Cassette.Finish(); err != nil {
+      errs = append(errs, err)
+    }
+  }
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
+  }
+  return errs
+}
+
+// CloseOutbound abandons any test that did not reach FinishOutbound
+// and stops the Upstream.
+func (o *Outbound) CloseOutbound() {
+  o.abandonOutbound()
+  if o.Upstream != nil {
+    o.Upstream.Close()
+  }
+}
+
+// abandonOutbound ends any test in progress, ignoring mismatches,
+// since the test has already failed. A cassette being recorded is
+// discarded rather than written, so that a failed test does not
+// replace a good cassette with a partial one.
+func (o *Outbound) abandonOutbound() {
+  if o.Upstream != nil {
+    o.Upstream.Finish()
+  }
+  if o.Cassette != nil {
+    o.Cassette.discard()
+  }
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+)
+
+// Serve calls the handler with the request and returns the body of the
+// response. It returns an error if the response status is not OK or the
+// body is empty.
+// If useServer is false, it calls the handler directly with an
+// httptest.ResponseRecorder. If useServer is true, it starts the handler
+// on an httptest.Server listening on the loopback interface, sends the
+// request to that server with a real http.Client, and closes the server
+// when done, so that the request goes through the real transport.
+// The request URL may be relative, such as "/api/foo/".
+func Serve(handler http.Handler, req *http.Request, useServer bool) ([]byte, error) {
+  if useServer {
+    return serveNetwork(handler, req)
+  }
+  rr := httptest.NewRecorder()
+  handler.ServeHTTP(rr, req)
+  return checkResponse(req, rr.Code, rr.Body.Bytes())
+}
+
+// ServeRequest calls callback to create a request, sends it with ctx
+// to the handler as Serve does, and writes the body of the response to w.
+// The HTTP testers use it in Act.
+func ServeRequest(ctx context.Context, handler http.Handler, callback func() (*http.Request, error), useServer bool, w io.Writer) error {
+  req, err := callback()
+  if err != nil {
+    return fmt.Errorf("error calling callback in Tester.Act: %v", err)
+  }
+  body, err := Serve(handler, req.WithContext(ctx), useServer)
+  if err != nil {
+    return err
+  }
+  _, err = w.Write(body)
+  return err
+}
+
+// serveNetwork sends the request to the handler running on a loopback server.
+func serveNetwork(handler http.Handler, req *http.Request) ([]byte, error) {
+  server := httptest.NewServer(handler)
+  defer server.Close()
+
+  outreq := req.Clone(req.Context())
+  u := *req.URL
+  u.Scheme = "http"
+  u.Host = server.Listener.Addr().String()
+  outreq.URL = &u
+  outreq.Host = ""
+  outreq.RequestURI = ""
+
+  resp, err := server.Client().Do(outreq)
+  if err != nil {
+    return nil, fmt.Errorf("error sending request %v: %v", req.URL, err)
+  }
+  defer resp.Body.Close()
+  body, err := ioutil.ReadAll(resp.Body)
+  if err != nil {
+    return nil, fmt.Errorf("error reading response body for request %v: %v", req.URL, err)
+  }
+  return checkResponse(req, resp.StatusCode, body)
+}
+
+// checkResponse returns the body if the status is OK and the body is not empty.
+func checkResponse(req *http.Request, code int, body []byte) ([]byte, error) {
+  if got, want := code, http.StatusOK; got != want {
+    return nil, fmt.Errorf("HTTP response status for request %v: got %d, want %d\nBody: %v",
+        req.URL, got, want, string(body))
+  }
+  if len(body) == 0 {
+    return nil, errors.New("response body should not be empty")
+  }
+  return body, nil
+}
@@ -1,12 +1,8 @@
 package http
 
 import (
-  "errors"
-  "fmt"
-  "io/ioutil"
+  "context"
   "net/http"
-  "net/http/httptest"
-  "os"
 
   goldenbase "github.com/jimmc/golden/base"
 )
@@ -22,11 +18,21 @@ import (
 //   if err := r.RunTestWith(t, basename2, callback2); err != nil { t.Fatal(err) }
 //   if err := r.Close(); err != nil { t.Fatal(err) }
 // (Error handling should be better than the minimal error handling shown above.)
+// The handler under test is either created for each test by CreateHandler
+// or, when CreateHandler is not set, is the fixture given to NewHandlerTester.
 type Tester struct {
-  goldenbase.Tester
+  goldenbase.FixtureTester[http.Handler]
 
   CreateHandler func(r *Tester) http.Handler
   Callback func() (*http.Request, error)
+
+  // If true, the handler is run on a loopback httptest.Server and the
+  // request is sent with a real http.Client, rather than calling the
+  // handler directly with an httptest.ResponseRecorder.
+  UseServer bool
+
+  // The fake services the handler sends its outbound requests to.
+  Outbound
 }
 
 type TesterApi interface {
@@ -42,40 +48,64 @@ func NewTester(createHandler func(r *Tester) http.Handler) *Tester {
   return r
 }
 
+// NewHandlerTester creates a new instance of a Tester that will send its
+// requests to the specified handler, which is kept as the fixture.
+func NewHandlerTester(handler http.Handler) *Tester {
+  r := &Tester{}
+  r.Fixture = handler
+  return r
+}
+
 // SetBaseNameAndCallback resets the basename and callback of the Tester in preparation for running a test.
 func (r *Tester) SetBaseNameAndCallback(basename string, callback func() (*http.Request, error)) {
   r.BaseName = basename
   r.Callback = callback
 }
 
+// Arrange sets up for one test and starts it on the Upstream and the Cassette, if they are set.
+func (r *Tester) Arrange() error {
+  if err := r.Tester.Arrange(); err != nil {
+    return err
+  }
+  return r.StartOutbound(&r.Tester)
+}
+
 // Act sets up the handler, calls the request, and records the result to the output file.
 func (r *Tester) Act() error {
-  handler := r.CreateHandler(r)
-
-  req, err := r.Callback()
-  if err != nil {
-    return fmt.Errorf("error calling callback in Tester.Act: %v", err)
+  handler := r.Fixture
+  if r.CreateHandler != nil {
+    handler = r.CreateHandler(r)
   }
+  return ServeRequest(r.Context(), handler, r.Callback, r.UseServer, r.OutW)
+}
 
-  rr := httptest.NewRecorder()
-  handler.ServeHTTP(rr, req)
-
-  if got, want := rr.Code, http.StatusOK; got != want {
-    return fmt.Errorf("HTTP response status for request %v: got %d, want %d\nBody: %v",
-        req.URL, got, want, rr.Body.String())
+// Assert finishes the test on the Upstream and the Cassette, if they are set, and
+// compares the outputs to their golden files. It also reports canned
+// upstream responses that were not used and cassette requests that did
+// not match. If there are several problems, it returns a
+// goldenbase.MultiError that reports all of them.
+func (r *Tester) Assert() error {
+  errs := make(goldenbase.MultiError, 0)
+  if err := r.FinishOutbound(); err != nil {
+    errs = append(errs, err)
   }
-
-  body := rr.Body.Bytes()
-  if len(body) == 0 {
-    return errors.New("response body should not be empty")
+  if err := r.Tester.Assert(); err != nil {
+    errs = append(errs, err)
   }
-
-  outfilepath := r.OutFilePath()
-  os.Remove(outfilepath)
-  if err := ioutil.WriteFile(outfilepath, body, 0644); err != nil {
-    return err
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
   }
-  return nil
+  return errs
+}
+
+// Close finishes the test on the Upstream and the Cassette, in case the last
+// test did not reach Assert, and stops the Upstream, then closes the Tester.
+func (r *Tester) Close() error {
+  r.CloseOutbound()
+  return r.Tester.Close()
 }
 
 // RunTestWith runs a test using the specified basename and callback.
@@ -86,13 +116,23 @@ func RunTestWith(r TesterApi, basename string, callback func() (*http.Request, e
   return goldenbase.RunTest(r)
 }
 
-// Run initializes the tester, runs a test, and closes it, calling Fatalf on any error.
+// RunTestContextWith is like RunTestWith but passes ctx to the request
+// and fails the test if ctx is done before the test finishes.
+func RunTestContextWith(ctx context.Context, r TesterApi, basename string, callback func() (*http.Request, error)) error {
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunTestContext(ctx, r)
+}
+
+// RunOneWith initializes the tester, runs a test using the specified basename
+// and callback, and closes it, as goldenbase.RunOne does.
 func RunOneWith(r TesterApi, basename string, callback func() (*http.Request, error)) error {
-  if err := r.Init(); err != nil {
-    return err
-  }
-  if err := RunTestWith(r, basename, callback); err != nil {
-    return err
-  }
-  return r.Close()
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunOne(r)
+}
+
+// RunOneContextWith is like RunOneWith but passes ctx to the request
+// and fails the test if ctx is done before the request finishes.
+func RunOneContextWith(ctx context.Context, r TesterApi, basename string, callback func() (*http.Request, error)) error {
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunOneContext(ctx, r)
 }
@@ -1,9 +1,18 @@
 package http_test
 
 import (
+  "context"
+  "errors"
+  "fmt"
+  "io/ioutil"
   "net/http"
+  "os"
+  "path/filepath"
+  "strings"
   "testing"
+  "time"
 
+  goldenbase "github.com/jimmc/golden/base"
   goldenhttp "github.com/jimmc/golden/http"
 )
 
@@ -27,3 +36,330 @@ func TestHttpTester(t *testing.T) {
     t.Fatalf("Error in Run: %s", err)
   }
 }
+
+func TestHttpHandlerTester(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttp.NewHandlerTester(&handler{})
+  if err := goldenhttp.RunOneWith(r, "foo", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+func TestHttpTesterObservesInitAndClose(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttp.NewTester(createTestHandler)
+  phases := []string{}
+  r.Observers = []goldenbase.Observer{goldenbase.ObserverFuncs{
+    Before: func(r goldenbase.Runner, phase goldenbase.Phase) {
+      phases = append(phases, string(phase))
+    },
+  }}
+  if err := goldenhttp.RunOneWith(r, "foo", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+  if got, want := strings.Join(phases, " "), "Init Arrange Act Assert Close"; got != want {
+    t.Errorf("observed phases: got %q, want %q", got, want)
+  }
+}
+
+type slowHandler struct {}
+
+func (h *slowHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  <-req.Context().Done()
+  w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+func TestHttpTesterTimeout(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/slow/", nil)
+  }
+  r := goldenhttp.NewTester(func(r *goldenhttp.Tester) http.Handler {
+    return &slowHandler{}
+  })
+  ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+  defer cancel()
+  err := goldenhttp.RunTestContextWith(ctx, r, "slow", request)
+  if !errors.Is(err, context.DeadlineExceeded) {
+    t.Fatalf("expected deadline exceeded, got %v", err)
+  }
+  if got, want := err.Error(), "error in test Act: test slow did not finish Act"; !strings.HasPrefix(got, want) {
+    t.Errorf("error: got %q, want prefix %q", got, want)
+  }
+}
+
+func TestHttpTesterRunOneTimeout(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/slow/", nil)
+  }
+  r := goldenhttp.NewHandlerTester(&slowHandler{})
+  ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+  defer cancel()
+  if err := goldenhttp.RunOneContextWith(ctx, r, "slow", request); !errors.Is(err, context.DeadlineExceeded) {
+    t.Fatalf("expected deadline exceeded, got %v", err)
+  }
+}
+
+func TestHttpTesterServer(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttp.NewTester(createTestHandler)
+  r.UseServer = true
+  r.GoldenBaseName = "foo"
+  if err := goldenhttp.RunOneWith(r, "foo-server", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+// upstreamHandler calls an upstream service and returns what it got back.
+type upstreamHandler struct {
+  client *http.Client
+  baseURL string
+}
+
+func (h *upstreamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  for _, name := range []string{"alice", "bob"} {
+    upreq, err := http.NewRequest("POST", h.baseURL + "/users?q=1", strings.NewReader(`{"name": "` + name + `"}`))
+    if err != nil {
+      http.Error(w, err.Error(), http.StatusInternalServerError)
+      return
+    }
+    upreq.Header.Set("Content-Type", "application/json")
+    resp, err := h.client.Do(upreq)
+    if err != nil {
+      http.Error(w, err.Error(), http.StatusBadGateway)
+      return
+    }
+    body, err := ioutil.ReadAll(resp.Body)
+    resp.Body.Close()
+    if err != nil {
+      http.Error(w, err.Error(), http.StatusBadGateway)
+      return
+    }
+    fmt.Fprintf(w, "%d %s\n", resp.StatusCode, body)
+  }
+}
+
+func TestHttpTesterUpstream(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/users/", nil)
+  }
+  for _, useServer := range []bool{false, true} {
+    upstream := goldenhttp.NewUpstream()
+    r := goldenhttp.NewTester(func(r *goldenhttp.Tester) http.Handler {
+      if useServer {
+        return &upstreamHandler{client: http.DefaultClient, baseURL: upstream.URL()}
+      }
+      return &upstreamHandler{client: upstream.Client(), baseURL: "http://users.example.com"}
+    })
+    r.Upstream = upstream
+    basename := "upstream"
+    if useServer {
+      basename = "upstream-server"
+    }
+    if err := goldenhttp.RunOneWith(r, basename, request); err != nil {
+      t.Fatalf("Error in Run: %s", err)
+    }
+  }
+}
+
+func TestUpstreamUnusedResponse(t *testing.T) {
+  prefix := filepath.Join(t.TempDir(), "unused.upstream")
+  u := goldenhttp.NewUpstream()
+  defer u.Close()
+  u.Start(prefix, ioutil.Discard)
+  if err := ioutil.WriteFile(u.ResponseFilePath(1), []byte("HTTP/1.1 200 OK\n\nok\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+  if err := u.Finish(); err == nil || !strings.Contains(err.Error(), "not used") {
+    t.Errorf("expected unused response error, got %v", err)
+  }
+  if _, err := u.Client().Get("http://example.com/"); err == nil {
+    t.Errorf("expected error for request outside of a test")
+  }
+  u.Start(prefix, ioutil.Discard)
+  if _, err := u.Client().Get("http://example.com/"); err != nil {
+    t.Fatalf("error calling upstream: %v", err)
+  }
+  if _, err := u.Client().Get("http://example.com/"); err == nil {
+    t.Errorf("expected error for missing canned response")
+  }
+  if err := u.Finish(); err != nil {
+    t.Errorf("Finish: %v", err)
+  }
+}
+
+func TestAssertReportsGoldenAndUpstreamErrors(t *testing.T) {
+  update := *goldenbase.UpdateGolden
+  *goldenbase.UpdateGolden = false
+  defer func() { *goldenbase.UpdateGolden = update }()
+  dir := t.TempDir()
+  for name, content := range map[string]string{
+    "both.golden": "Other response",
+    "both.upstream.golden": "",
+    "both.upstream-1.response": "HTTP/1.1 200 OK\n\nok\n",
+  } {
+    if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+  r := goldenhttp.NewTester(createTestHandler)
+  r.Upstream = goldenhttp.NewUpstream()
+  r.BaseDir = dir
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo", nil)
+  }
+  err := goldenhttp.RunOneWith(r, "both", request)
+  r.Close()
+  if err == nil {
+    t.Fatalf("Expected golden mismatch and unused response errors")
+  }
+  for _, want := range []string{"does not match golden file", "upstream canned responses not used"} {
+    if !strings.Contains(err.Error(), want) {
+      t.Errorf("RunOneWith error %q does not contain %q", err, want)
+    }
+  }
+}
+
+func TestUpstreamCloseAfterActFails(t *testing.T) {
+  upstream := goldenhttp.NewUpstream()
+  r := goldenhttp.NewTester(func(r *goldenhttp.Tester) http.Handler {
+    return &upstreamHandler{client: http.DefaultClient, baseURL: upstream.URL()}
+  })
+  r.Upstream = upstream
+  request := func() (*http.Request, error) {
+    return nil, errors.New("no request")
+  }
+  if err := goldenhttp.RunOneWith(r, "upstream-fail", request); err == nil {
+    t.Fatalf("Expected error from request callback")
+  }
+  if err := r.Close(); err != nil {
+    t.Fatalf("Close: %v", err)
+  }
+}
+
+// usersService is a stand-in for an upstream service, used when recording cassettes.
+type usersService struct {}
+
+func (s *usersService) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  body, _ := ioutil.ReadAll(req.Body)
+  w.Header().Set("Content-Type", "application/json")
+  w.Header().Set("Set-Cookie", "session=secret")
+  if strings.Contains(string(body), "bob") {
+    w.WriteHeader(http.StatusConflict)
+    w.Write([]byte(`{"error": "exists"}`))
+    return
+  }
+  w.Write([]byte(`{"id": 1}`))
+}
+
+func TestHttpTesterCassette(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/users/", nil)
+  }
+  cassette := goldenhttp.NewCassette(goldenhttp.HandlerTransport(&usersService{}))
+  r := goldenhttp.NewTester(func(r *goldenhttp.Tester) http.Handler {
+    return &upstreamHandler{client: cassette.Client(), baseURL: "http://users.example.com"}
+  })
+  r.Cassette = cassette
+  if err := goldenhttp.RunOneWith(r, "cassette", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+func TestCassetteRecordAbandoned(t *testing.T) {
+  record := *goldenhttp.RecordCassettes
+  *goldenhttp.RecordCassettes = true
+  defer func() { *goldenhttp.RecordCassettes = record }()
+  cassette := goldenhttp.NewCassette(goldenhttp.HandlerTransport(&usersService{}))
+  r := goldenhttp.NewTester(func(r *goldenhttp.Tester) http.Handler {
+    return &upstreamHandler{client: cassette.Client(), baseURL: "http://users.example.com"}
+  })
+  r.Cassette = cassette
+  r.BaseDir = t.TempDir()
+  request := func() (*http.Request, error) {
+    return nil, errors.New("no request")
+  }
+  if err := goldenhttp.RunOneWith(r, "abandoned", request); err == nil {
+    t.Fatalf("Expected error from request callback")
+  }
+  if err := r.Close(); err != nil {
+    t.Fatalf("Close: %v", err)
+  }
+  path := filepath.Join(r.BaseDir, "abandoned.cassette")
+  if _, err := os.Stat(path); !os.IsNotExist(err) {
+    t.Errorf("cassette %s was written for an abandoned test", path)
+  }
+}
+
+func TestCassetteMismatch(t *testing.T) {
+  c := goldenhttp.NewCassette(nil)
+  if err := c.Start("testdata/cassette.cassette", false); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := c.Client().Post("http://users.example.com/users?q=2", "application/json", strings.NewReader("{}")); err == nil {
+    t.Errorf("expected error for request that does not match")
+  }
+  err := c.Finish()
+  if err == nil || !strings.Contains(err.Error(), "url: recorded http://users.example.com/users?q=1, got http://users.example.com/users?q=2") {
+    t.Errorf("expected mismatch error, got %v", err)
+  }
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "users.cassette")
+  post := func(c *goldenhttp.Cassette) (*http.Response, error) {
+    req, err := http.NewRequest("POST", "http://users.example.com/users", strings.NewReader(`{"name": "alice"}`))
+    if err != nil {
+      return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer secret")
+    req.Header.Set("Cookie", "session=secret")
+    return c.Client().Do(req)
+  }
+
+  if err := goldenhttp.NewCassette(nil).Start(path, true); err == nil {
+    t.Errorf("expected error recording without a Target")
+  }
+
+  recorder := goldenhttp.NewCassette(goldenhttp.HandlerTransport(&usersService{}))
+  if err := recorder.Start(path, true); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := post(recorder); err != nil {
+    t.Fatalf("error recording request: %v", err)
+  }
+  if err := recorder.Finish(); err != nil {
+    t.Fatalf("error writing cassette: %v", err)
+  }
+  data, err := ioutil.ReadFile(path)
+  if err != nil {
+    t.Fatal(err)
+  }
+  for _, secret := range []string{"Authorization", "Cookie", "secret"} {
+    if strings.Contains(string(data), secret) {
+      t.Errorf("cassette contains %q:\n%s", secret, data)
+    }
+  }
+
+  player := goldenhttp.NewCassette(nil)
+  if err := player.Start(path, false); err != nil {
+    t.Fatal(err)
+  }
+  resp, err := post(player)
+  if err != nil {
+    t.Fatalf("error replaying request: %v", err)
+  }
+  body, _ := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if got, want := string(body), `{"id": 1}`; got != want {
+    t.Errorf("replayed body: got %q, want %q", got, want)
+  }
+  if err := player.Finish(); err != nil {
+    t.Errorf("error finishing replay: %v", err)
+  }
+}
@@ -0,0 +1,217 @@
+package http
+
+import (
+  "bufio"
+  "bytes"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "path/filepath"
+  "sort"
+  "strings"
+  "sync"
+
+  goldenbase "github.com/jimmc/golden/base"
+)
+
+// UpstreamHost is the host written in the transcript for requests
+// made to the server started by Upstream.URL.
+const UpstreamHost = "upstream"
+
+// Upstream is a fake upstream service for testing the outbound HTTP
+// requests made by the code under test. It can be used as an
+// http.RoundTripper, through Client, or as a local server, through URL.
+// During each test, started by Start and ended by Finish, it records each
+// request in a transcript and answers the Nth request with the canned
+// HTTP response in the file <prefix>-N.response, such as
+// testdata/foo.upstream-1.response. A response file contains a complete
+// HTTP response, for example:
+//   HTTP/1.1 200 OK
+//   Content-Type: application/json
+//
+//   {"id": 1}
+// The same Upstream can be used for a sequence of tests, so that the code
+// under test can be given its client or URL once.
+type Upstream struct {
+  // Request headers that are not written to the transcript.
+  IgnoreHeaders []string
+
+  mu sync.Mutex
+  started bool
+  prefix string
+  transcript io.Writer
+  count int
+  server *httptest.Server
+}
+
+// NewUpstream creates an Upstream. By default the User-Agent,
+// Accept-Encoding and Content-Length headers, which are added by the
+// HTTP transport, are not written to the transcript.
+func NewUpstream() *Upstream {
+  return &Upstream{
+    IgnoreHeaders: []string{"User-Agent", "Accept-Encoding", "Content-Length"},
+  }
+}
+
+// Start starts a test in which the Upstream reads its canned responses
+// from files starting with prefix and writes its transcript to w.
+func (u *Upstream) Start(prefix string, w io.Writer) {
+  u.mu.Lock()
+  defer u.mu.Unlock()
+  u.started = true
+  u.prefix = prefix
+  u.transcript = w
+  u.count = 0
+}
+
+// Finish ends the test started by Start. It returns an error if there
+// are canned response files that were not used.
+func (u *Upstream) Finish() error {
+  u.mu.Lock()
+  defer u.mu.Unlock()
+  if !u.started {
+    return nil
+  }
+  u.started = false
+  paths, err := filepath.Glob(u.prefix + "-*.response")
+  if err != nil {
+    return err
+  }
+  unused := make([]string, 0)
+  for _, path := range paths {
+    var n int
+    if _, err := fmt.Sscanf(strings.TrimPrefix(path, u.prefix + "-"), "%d.response", &n); err == nil && n > u.count {
+      unused = append(unused, path)
+    }
+  }
+  if len(unused) > 0 {
+    sort.Strings(unused)
+    return fmt.Errorf("upstream canned responses not used: %s", strings.Join(unused, ", "))
+  }
+  return nil
+}
+
+// Close ends any test without checking for unused responses, and stops
+// the server if it was started.
+func (u *Upstream) Close() {
+  u.mu.Lock()
+  u.started = false
+  server := u.server
+  u.server = nil
+  u.mu.Unlock()
+  // The lock must not be held here, since Close waits for requests
+  // in progress, which need the lock.
+  if server != nil {
+    server.Close()
+  }
+}
+
+// ResponseFilePath returns the path of the canned response file
+// for the nth request, starting at 1.
+func (u *Upstream) ResponseFilePath(n int) string {
+  return fmt.Sprintf("%s-%d.response", u.prefix, n)
+}
+
+// RoundTrip records the request and returns the next canned response.
+func (u *Upstream) RoundTrip(req *http.Request) (*http.Response, error) {
+  return u.respond(req, req.URL.String())
+}
+
+// Client returns an http.Client that sends its requests to the Upstream.
+func (u *Upstream) Client() *http.Client {
+  return &http.Client{Transport: u}
+}
+
+// URL starts a loopback server for the Upstream, if it is not already
+// running, and returns its base URL. Requests to the server are recorded
+// with UpstreamHost in place of the server's address.
+func (u *Upstream) URL() string {
+  u.mu.Lock()
+  defer u.mu.Unlock()
+  if u.server == nil {
+    u.server = httptest.NewServer(http.HandlerFunc(u.serveHTTP))
+  }
+  return u.server.URL
+}
+
+// serveHTTP records the request to the server and writes the next canned response.
+func (u *Upstream) serveHTTP(w http.ResponseWriter, req *http.Request) {
+  resp, err := u.respond(req, "http://" + UpstreamHost + req.URL.RequestURI())
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+  defer resp.Body.Close()
+  for key, values := range resp.Header {
+    for _, value := range values {
+      w.Header().Add(key, value)
+    }
+  }
+  w.WriteHeader(resp.StatusCode)
+  io.Copy(w, resp.Body)
+}
+
+// respond writes the request to the transcript and reads the canned response.
+func (u *Upstream) respond(req *http.Request, url string) (*http.Response, error) {
+  var body []byte
+  if req.Body != nil {
+    var err error
+    body, err = ioutil.ReadAll(req.Body)
+    req.Body.Close()
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  u.mu.Lock()
+  defer u.mu.Unlock()
+  if !u.started {
+    return nil, fmt.Errorf("upstream request %s %s made outside of a test", req.Method, url)
+  }
+  u.count++
+  fmt.Fprintf(u.transcript, "request %d\n%s %s\n", u.count, req.Method, url)
+  keys := make([]string, 0, len(req.Header))
+  for key := range req.Header {
+    if !u.ignoreHeader(key) {
+      keys = append(keys, key)
+    }
+  }
+  sort.Strings(keys)
+  for _, key := range keys {
+    for _, value := range req.Header[key] {
+      fmt.Fprintf(u.transcript, "%s: %s\n", key, value)
+    }
+  }
+  if len(body) > 0 {
+    fmt.Fprintf(u.transcript, "\n%s", body)
+    if !bytes.HasSuffix(body, []byte("\n")) {
+      fmt.Fprintln(u.transcript)
+    }
+  }
+  fmt.Fprintln(u.transcript)
+
+  filename := u.ResponseFilePath(u.count)
+  goldenbase.RecordUsed(filename)
+  content, err := ioutil.ReadFile(filename)
+  if err != nil {
+    return nil, fmt.Errorf("no canned response for upstream request %d %s %s: %v",
+        u.count, req.Method, url, err)
+  }
+  resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(content)), req)
+  if err != nil {
+    return nil, fmt.Errorf("error reading canned response file %s: %v", filename, err)
+  }
+  return resp, nil
+}
+
+// ignoreHeader returns true if the header is in IgnoreHeaders.
+func (u *Upstream) ignoreHeader(key string) bool {
+  for _, h := range u.IgnoreHeaders {
+    if http.CanonicalHeaderKey(h) == key {
+      return true
+    }
+  }
+  return false
+}
@@ -1,15 +1,13 @@
 package httpdb
 
 import (
-  "errors"
-  "fmt"
-  "io/ioutil"
+  "context"
+  "database/sql"
   "net/http"
-  "net/http/httptest"
-  "os"
 
   goldenbase "github.com/jimmc/golden/base"
   goldendb "github.com/jimmc/golden/db"
+  goldenhttp "github.com/jimmc/golden/http"
 )
 
 // Tester provides the structure for running API unit tests.
@@ -22,11 +20,23 @@ import (
 //   r.RunTestWith(t, basename, callback)
 //   r.RunTestWith(t, basename2, callback2)
 //   r.Close()
+// The handler under test is created for each test by CreateHandler, or,
+// when that is not set, by CreateDbHandler from the database fixture.
 type Tester struct {
   goldendb.Tester
 
   CreateHandler func(r *Tester) http.Handler
+  // Function to create the handler under test for the database;
+  // used when CreateHandler is not set. See NewDbHandlerTester.
+  CreateDbHandler func(db *sql.DB) http.Handler
   Callback func() (*http.Request, error)
+
+  // If true, the handler is run on a loopback httptest.Server and the
+  // request is sent with a real http.Client; see goldenhttp.Serve.
+  UseServer bool
+
+  // The fake services the handler sends its outbound requests to.
+  goldenhttp.Outbound
 }
 
 type TesterApi interface {
@@ -42,40 +52,73 @@ func NewTester(createHandler func(r *Tester) http.Handler) *Tester {
   return r
 }
 
+// NewDbHandlerTester creates a new instance of a Tester that will use the
+// specified function to create an http.Handler for the database fixture,
+// so that the function does not need to capture the Tester.
+func NewDbHandlerTester(createHandler func(db *sql.DB) http.Handler) *Tester {
+  r := &Tester{}
+  r.CreateDbHandler = createHandler
+  return r
+}
+
 // SetBaseNameAndCallback resets the basename and callback of the Tester in preparation for running a test.
 func (r *Tester) SetBaseNameAndCallback(basename string, callback func() (*http.Request, error)) {
   r.BaseName = basename
   r.Callback = callback
 }
 
+// Arrange sets up for one test and starts it on the Upstream and the Cassette, if they are set.
+func (r *Tester) Arrange() error {
+  if err := r.Tester.Arrange(); err != nil {
+    return err
+  }
+  return r.StartOutbound(&r.Tester.Tester)
+}
+
 // Act sets up the handler, calls the request, and records the result to the output file.
+// If LogQueries is set, the SQL statements executed by the handler are recorded.
 func (r *Tester) Act() error {
-  handler := r.CreateHandler(r)
+  return r.ActWithQueryLog(r.serve)
+}
 
-  req, err := r.Callback()
-  if err != nil {
-    return fmt.Errorf("error calling callback in Tester.Act: %v", err)
+// serve sets up the handler, calls the request, and writes the response body to the output.
+func (r *Tester) serve() error {
+  var handler http.Handler
+  if r.CreateHandler != nil {
+    handler = r.CreateHandler(r)
+  } else {
+    handler = r.CreateDbHandler(r.Fixture)
   }
+  return goldenhttp.ServeRequest(r.Context(), handler, r.Callback, r.UseServer, r.OutW)
+}
 
-  rr := httptest.NewRecorder()
-  handler.ServeHTTP(rr, req)
-
-  if got, want := rr.Code, http.StatusOK; got != want {
-    return fmt.Errorf("HTTP response status for request %v: got %d, want %d\nBody: %v",
-        req.URL, got, want, rr.Body.String())
+// Assert finishes the test on the Upstream and the Cassette, if they are set, and
+// compares the outputs to their golden files. It also reports canned
+// upstream responses that were not used and cassette requests that did
+// not match. If there are several problems, it returns a
+// goldenbase.MultiError that reports all of them.
+func (r *Tester) Assert() error {
+  errs := make(goldenbase.MultiError, 0)
+  if err := r.FinishOutbound(); err != nil {
+    errs = append(errs, err)
   }
-
-  body := rr.Body.Bytes()
-  if len(body) == 0 {
-    return errors.New("response body should not be empty")
+  if err := r.Tester.Assert(); err != nil {
+    errs = append(errs, err)
   }
-
-  outfilepath := r.OutFilePath()
-  os.Remove(outfilepath)
-  if err := ioutil.WriteFile(outfilepath, body, 0644); err != nil {
-    return err
+  switch len(errs) {
+  case 0:
+    return nil
+  case 1:
+    return errs[0]
   }
-  return nil
+  return errs
+}
+
+// Close finishes the test on the Upstream and the Cassette, in case the last
+// test did not reach Assert, and stops the Upstream, then closes the Tester.
+func (r *Tester) Close() error {
+  r.CloseOutbound()
+  return r.Tester.Close()
 }
 
 // RunTestWith runs a test using the specified basename and callback.
@@ -86,13 +129,23 @@ func RunTestWith(r TesterApi, basename string, callback func() (*http.Request, e
   return goldenbase.RunTest(r)
 }
 
-// Run initializes the tester, runs a test, and closes it, calling Fatalf on any error.
+// RunTestContextWith is like RunTestWith but passes ctx to the request
+// and fails the test if ctx is done before the test finishes.
+func RunTestContextWith(ctx context.Context, r TesterApi, basename string, callback func() (*http.Request, error)) error {
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunTestContext(ctx, r)
+}
+
+// RunOneWith initializes the tester, runs a test using the specified basename
+// and callback, and closes it, as goldenbase.RunOne does.
 func RunOneWith(r TesterApi, basename string, callback func() (*http.Request, error)) error {
-  if err := r.Init(); err != nil {
-    return err
-  }
-  if err := RunTestWith(r, basename, callback); err != nil {
-    return err
-  }
-  return r.Close()
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunOne(r)
+}
+
+// RunOneContextWith is like RunOneWith but passes ctx to the request
+// and fails the test if ctx is done before the request finishes.
+func RunOneContextWith(ctx context.Context, r TesterApi, basename string, callback func() (*http.Request, error)) error {
+  r.SetBaseNameAndCallback(basename, callback)
+  return goldenbase.RunOneContext(ctx, r)
 }
@@ -3,9 +3,11 @@ package httpdb_test
 import (
   "database/sql"
   "fmt"
+  "io/ioutil"
   "net/http"
   "testing"
 
+  goldenhttp "github.com/jimmc/golden/http"
   goldenhttpdb "github.com/jimmc/golden/httpdb"
 )
 
@@ -68,3 +70,85 @@ func TestHttpDbTester(t *testing.T) {
     t.Fatalf("Error in Run: %s", err)
   }
 }
+
+func TestHttpDbHandlerTester(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttpdb.NewDbHandlerTester(func(db *sql.DB) http.Handler {
+    return &dbhandler{db: db}
+  })
+  r.GoldenBaseName = "foo-db"
+  r.SetupBaseName = "foo-db"
+  if err := goldenhttpdb.RunOneWith(r, "foo-db-fixture", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+func TestHttpDbQueryLog(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttpdb.NewTester(func (r *goldenhttpdb.Tester) http.Handler {
+    return &dbhandler{db: r.DB}
+  })
+  r.SetupBaseName = "foo-db"
+  r.GoldenBaseName = "foo-db"
+  r.LogQueries = true
+  if err := goldenhttpdb.RunOneWith(r, "foo-db-querylog", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+func TestHttpDbTesterServer(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  r := goldenhttpdb.NewTester(func (r *goldenhttpdb.Tester) http.Handler {
+    return &dbhandler{db: r.DB}
+  })
+  r.UseServer = true
+  r.SetupBaseName = "foo-db"
+  r.GoldenBaseName = "foo-db"
+  if err := goldenhttpdb.RunOneWith(r, "foo-db-server", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}
+
+// upstreamDbHandler fetches a title from an upstream service and writes it
+// before the rows of the database.
+type upstreamDbHandler struct {
+  dbhandler
+  client *http.Client
+}
+
+func (h *upstreamDbHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+  resp, err := h.client.Get("http://titles.example.com/title")
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadGateway)
+    return
+  }
+  title, err := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusBadGateway)
+    return
+  }
+  w.Write(title)
+  h.dbhandler.ServeHTTP(w, req)
+}
+
+func TestHttpDbTesterUpstream(t *testing.T) {
+  request := func() (*http.Request, error) {
+    return http.NewRequest("GET", "/api/foo/", nil)
+  }
+  upstream := goldenhttp.NewUpstream()
+  r := goldenhttpdb.NewTester(func (r *goldenhttpdb.Tester) http.Handler {
+    return &upstreamDbHandler{dbhandler: dbhandler{db: r.DB}, client: upstream.Client()}
+  })
+  r.Upstream = upstream
+  r.SetupBaseName = "foo-db"
+  if err := goldenhttpdb.RunOneWith(r, "foo-db-upstream", request); err != nil {
+    t.Fatalf("Error in Run: %s", err)
+  }
+}